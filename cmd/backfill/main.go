@@ -0,0 +1,75 @@
+package main
+
+import (
+	"avito-test-task/config"
+	"avito-test-task/internal/domain"
+	"avito-test-task/internal/repo"
+	"avito-test-task/internal/usecase"
+	"avito-test-task/pkg"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log"
+	"time"
+)
+
+// backfill fills derived data for rows stored before it existed:
+//   - duplicates: links flats to the listings they duplicate;
+//   - addresses: stores the normalized address used by the fuzzy address search.
+//
+// Run it from the repository root: go run ./cmd/backfill -job duplicates -batch 500
+func main() {
+	job := flag.String("job", "duplicates", "what to backfill: duplicates or addresses")
+	batchSize := flag.Int("batch", 500, "rows per batch")
+	flag.Parse()
+
+	if *job != "duplicates" && *job != "addresses" {
+		log.Fatalf("unknown backfill job %q", *job)
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		log.Fatal("can't read config file")
+	}
+
+	lg, err := pkg.CreateLogger(cfg.LogFile, "prod")
+	if err != nil {
+		log.Fatal("can't create logger")
+	}
+
+	connString := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", cfg.User, cfg.Password,
+		cfg.Host, cfg.Port, cfg.Db.Db)
+	pool, err := pgxpool.New(context.Background(), connString)
+	if err != nil {
+		log.Fatalf("can't connect to postgresql: %v", err.Error())
+	}
+	defer pool.Close()
+
+	retryAdapter := repo.NewPostgresRetryAdapter(pool, 3, time.Second*3)
+
+	if *job == "addresses" {
+		// the backfill sends no notifications: the notifying goroutine exits at once
+		done := make(chan bool, 1)
+		done <- true
+		houseRepo := repo.NewPostgresHouseRepo(pool, retryAdapter)
+		houseUsecase := usecase.NewHouseUsecase(houseRepo, nil, nil, "", done, 0, 0, lg)
+
+		updated, err := houseUsecase.BackfillAddresses(context.Background(), *batchSize, lg)
+		if err != nil {
+			log.Fatalf("backfill stopped after %d houses: %v", updated, err.Error())
+		}
+		fmt.Printf("done: %d house addresses normalized\n", updated)
+		return
+	}
+
+	flatRepo := repo.NewPostgresFlatRepo(pool, retryAdapter)
+	flatUsecase := usecase.NewFlatUsecase(flatRepo, time.Duration(cfg.LeaseSec)*time.Second,
+		[]domain.DeclineReason{}, nil)
+
+	marked, err := flatUsecase.BackfillDuplicates(context.Background(), *batchSize, lg)
+	if err != nil {
+		log.Fatalf("backfill stopped after %d duplicates: %v", marked, err.Error())
+	}
+	fmt.Printf("done: %d flats marked as duplicates\n", marked)
+}
@@ -6,9 +6,13 @@ import (
 )
 
 type Config struct {
-	Logger `yaml:"logger"`
-	Db     `yaml:"postgres"`
-	Secret `yaml:"secret"`
+	Logger     `yaml:"logger"`
+	Db         `yaml:"postgres"`
+	Secret     `yaml:"secret"`
+	Moderation `yaml:"moderation"`
+	Notify     `yaml:"notify"`
+	// SMTP is a named field: its Host, Port, User and Password would clash with Db ones if embedded
+	SMTP SMTP `yaml:"smtp"`
 }
 
 type Logger struct {
@@ -20,6 +24,59 @@ type Secret struct {
 	Key string `yaml:"key"`
 }
 
+type Moderation struct {
+	LeaseSec          int             `yaml:"lease-sec" env-default:"900"`
+	SweepFrequencySec int             `yaml:"sweep-frequency-sec" env-default:"30"`
+	DeclineReasons    []DeclineReason `yaml:"decline-reasons"`
+	RulesFile         string          `yaml:"rules-file" env-default:"config/moderation_rules.yml"`
+}
+
+type Notify struct {
+	// UnsubscribeURL is the public address of /unsubscribe put into notifications
+	UnsubscribeURL string `yaml:"unsubscribe-url" env:"UNSUBSCRIBE_URL" env-default:"http://localhost/unsubscribe"`
+}
+
+type SMTP struct {
+	// Host left empty keeps the stub sender that only prints notifications
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from" env:"SMTP_FROM"`
+	Subject  string `yaml:"subject" env:"SMTP_SUBJECT" env-default:"New flat in your house"`
+	// TLS is "starttls", "tls" or "none"
+	TLS                string `yaml:"tls" env:"SMTP_TLS" env-default:"starttls"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify" env:"SMTP_INSECURE_SKIP_VERIFY"`
+	TimeoutSec         int    `yaml:"timeout-sec" env:"SMTP_TIMEOUT_SEC" env-default:"10"`
+}
+
+type ModerationRules struct {
+	Rules []ModerationRule `yaml:"rules"`
+}
+
+type ModerationRule struct {
+	Name       string `yaml:"name"`
+	Kind       string `yaml:"kind"`
+	Outcome    string `yaml:"outcome"`
+	ReasonCode string `yaml:"reason-code"`
+
+	MaxDeviation float64 `yaml:"max-deviation"`
+	MinFlats     int     `yaml:"min-flats"`
+
+	Words []string `yaml:"words"`
+
+	MaxRooms        int `yaml:"max-rooms"`
+	MinPricePerRoom int `yaml:"min-price-per-room"`
+	MaxPricePerRoom int `yaml:"max-price-per-room"`
+
+	Owners []string `yaml:"owners"`
+}
+
+type DeclineReason struct {
+	Code  string `yaml:"code"`
+	Title string `yaml:"title"`
+}
+
 type Db struct {
 	Host         string `yaml:"host" env:"HOST" env-default:"localhost"`
 	Port         int    `yaml:"port"`
@@ -33,12 +90,22 @@ func ReadConfig() (*Config, error) {
 	cfg := Config{}
 	err := cleanenv.ReadConfig("config/config.yml", &cfg)
 	if err != nil {
-		return nil, fmt.Errorf("read config error: %v", err.Error)
+		return nil, fmt.Errorf("read config error: %v", err.Error())
 	}
 	err = cleanenv.ReadEnv(&cfg)
 	if err != nil {
-		return nil, fmt.Errorf("read env error: %v", err.Error)
+		return nil, fmt.Errorf("read env error: %v", err.Error())
 	}
 
 	return &cfg, nil
 }
+
+func ReadModerationRules(path string) (*ModerationRules, error) {
+	rules := ModerationRules{}
+	err := cleanenv.ReadConfig(path, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("read moderation rules error: %v", err.Error())
+	}
+
+	return &rules, nil
+}
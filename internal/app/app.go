@@ -4,6 +4,7 @@ import (
 	"avito-test-task/config"
 	"avito-test-task/internal/delivery/handlers"
 	mdware "avito-test-task/internal/delivery/middleware"
+	"avito-test-task/internal/domain"
 	"avito-test-task/internal/ports"
 	"avito-test-task/internal/repo"
 	"avito-test-task/internal/usecase"
@@ -12,6 +13,7 @@ import (
 	"fmt"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"log"
 	"net/http"
@@ -25,6 +27,13 @@ func Run(cfg *config.Config) {
 	}
 	pkg.Key = cfg.Key
 
+	if cfg.LeaseSec < 1 {
+		log.Fatalf("bad moderation config: lease-sec must be at least 1, got %d", cfg.LeaseSec)
+	}
+	if cfg.SweepFrequencySec < 1 {
+		log.Fatalf("bad moderation config: sweep-frequency-sec must be at least 1, got %d", cfg.SweepFrequencySec)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -39,37 +48,104 @@ func Run(cfg *config.Config) {
 	retryAdapter := repo.NewPostgresRetryAdapter(pool, 3, time.Second*3)
 
 	notifyRepo := repo.NewPostgresNotifyRepo(pool, retryAdapter)
-	notifySender := ports.NewSender()
+	var notifySender domain.NotifySender = ports.NewSender()
+	if cfg.SMTP.Host != "" {
+		smtpSender, err := ports.NewSMTPSender(ports.SMTPSettings{
+			Host:               cfg.SMTP.Host,
+			Port:               cfg.SMTP.Port,
+			Username:           cfg.SMTP.Username,
+			Password:           cfg.SMTP.Password,
+			From:               cfg.SMTP.From,
+			Subject:            cfg.SMTP.Subject,
+			TLSMode:            cfg.SMTP.TLS,
+			InsecureSkipVerify: cfg.SMTP.InsecureSkipVerify,
+			Timeout:            time.Duration(cfg.SMTP.TimeoutSec) * time.Second,
+		})
+		if err != nil {
+			log.Fatalf("can't create smtp sender: %v", err.Error())
+		}
+		defer smtpSender.Close()
+		notifySender = smtpSender
+	}
 
 	done := make(chan bool, 1)
 	defer func() {
 		done <- true
 	}()
 	houseRepo := repo.NewPostgresHouseRepo(pool, retryAdapter)
-	houseUsecase := usecase.NewHouseUsecase(houseRepo, notifySender, notifyRepo, done,
+	houseUsecase := usecase.NewHouseUsecase(houseRepo, notifySender, notifyRepo, cfg.UnsubscribeURL, done,
 		5*time.Second, 5*time.Second, lg)
 	houseHandler := handlers.NewHouseHandler(houseUsecase, time.Duration(cfg.DbTimeoutSec)*time.Second, lg)
 
+	developerRepo := repo.NewPostgresDeveloperRepo(pool, retryAdapter)
+	developerUsecase := usecase.NewDeveloperUsecase(developerRepo, houseRepo)
+	developerHandler := handlers.NewDeveloperHandler(developerUsecase, time.Duration(cfg.DbTimeoutSec)*time.Second, lg)
+
 	userRepo := repo.NewPostrgesUserRepo(pool, retryAdapter)
 	userUsecase := usecase.NewUserUsecase(userRepo)
 	userHandler := handlers.NewUserHandler(userUsecase, time.Duration(cfg.DbTimeoutSec)*time.Second, lg)
 
 	flatRepo := repo.NewPostgresFlatRepo(pool, retryAdapter)
-	flatUsecase := usecase.NewFlatUsecase(flatRepo)
+	moderationLease := time.Duration(cfg.LeaseSec) * time.Second
+	declineReasons := make([]domain.DeclineReason, 0, len(cfg.DeclineReasons))
+	for _, reason := range cfg.DeclineReasons {
+		declineReasons = append(declineReasons, domain.DeclineReason{Code: reason.Code, Title: reason.Title})
+	}
+	autoModerator, err := newAutoModerator(cfg.RulesFile, declineReasons)
+	if err != nil {
+		log.Fatalf("can't load moderation rules: %v", err.Error())
+	}
+	flatUsecase := usecase.NewFlatUsecase(flatRepo, moderationLease, declineReasons, autoModerator)
 	flatHandler := handlers.NewFlatHandler(flatUsecase, time.Duration(cfg.DbTimeoutSec)*time.Second, lg)
 
+	moderationRepo := repo.NewPostgresModerationRepo(pool, retryAdapter)
+	sweepDone := make(chan bool, 1)
+	defer func() {
+		sweepDone <- true
+	}()
+	moderationUsecase := usecase.NewModerationUsecase(moderationRepo, moderationLease, sweepDone,
+		time.Duration(cfg.SweepFrequencySec)*time.Second, 5*time.Second, lg)
+	moderationHandler := handlers.NewModerationHandler(moderationUsecase, time.Duration(cfg.DbTimeoutSec)*time.Second, lg)
+
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Recoverer)
 
 	r.Post("/house/create", mdware.AuthMiddleware(mdware.AccessMiddleware(houseHandler.Create)))
 	r.Get("/house/{id}", mdware.AuthMiddleware(houseHandler.GetFlatsByID))
+	r.Patch("/house/{id}", mdware.AuthMiddleware(mdware.AccessMiddleware(houseHandler.Edit)))
+	r.Delete("/house/{id}", mdware.AuthMiddleware(mdware.AccessMiddleware(houseHandler.Delete)))
+	r.Get("/house/{id}/stats", mdware.AuthMiddleware(houseHandler.GetStats))
+	r.Get("/houses", mdware.AuthMiddleware(houseHandler.Search))
+	r.Get("/houses/nearby", mdware.AuthMiddleware(houseHandler.Nearby))
+	r.Get("/houses/search", mdware.AuthMiddleware(houseHandler.SearchByAddress))
+	r.Post("/developers", mdware.AuthMiddleware(mdware.AccessMiddleware(developerHandler.Create)))
+	r.Get("/developers", mdware.AuthMiddleware(developerHandler.GetAll))
+	r.Get("/developers/{id}", mdware.AuthMiddleware(developerHandler.GetByID))
+	r.Patch("/developers/{id}", mdware.AuthMiddleware(mdware.AccessMiddleware(developerHandler.Update)))
+	r.Delete("/developers/{id}", mdware.AuthMiddleware(mdware.AccessMiddleware(developerHandler.Delete)))
+	r.Get("/developers/{id}/houses", mdware.AuthMiddleware(developerHandler.GetHouses))
 	r.Get("/dummyLogin", userHandler.DummyLogin)
 	r.Post("/register", userHandler.Register)
 	r.Post("/login", userHandler.Login)
 	r.Post("/flat/update", mdware.AuthMiddleware(mdware.AccessMiddleware(flatHandler.Update)))
 	r.Post("/flat/create", mdware.AuthMiddleware(flatHandler.Create))
+	r.Get("/flats", mdware.AuthMiddleware(flatHandler.Search))
+	r.Post("/flat/edit", mdware.AuthMiddleware(flatHandler.Edit))
+	r.Post("/flat/archive", mdware.AuthMiddleware(flatHandler.Archive))
+	r.Post("/flat/restore", mdware.AuthMiddleware(flatHandler.Restore))
+	r.Get("/flat/{house_id}/{flat_id}/edits", mdware.AuthMiddleware(mdware.AccessMiddleware(flatHandler.GetEdits)))
+	r.Get("/flat/{house_id}/{flat_id}/history", mdware.AuthMiddleware(flatHandler.GetHistory))
+	r.Get("/flat/{house_id}/{flat_id}", mdware.AuthMiddleware(flatHandler.GetDetail))
+	r.Get("/me/flats", mdware.AuthMiddleware(flatHandler.GetOwnFlats))
 	r.Post("/house/{id}/subscribe", mdware.AuthMiddleware(houseHandler.Subscribe))
+	r.Delete("/house/{id}/subscribe", mdware.AuthMiddleware(houseHandler.Unsubscribe))
+	r.Get("/me/subscriptions", mdware.AuthMiddleware(houseHandler.GetSubscriptions))
+	r.Get("/unsubscribe", houseHandler.CheckUnsubscribeToken)
+	r.Post("/unsubscribe", houseHandler.UnsubscribeByToken)
+	r.Post("/moderation/claim", mdware.AuthMiddleware(mdware.AccessMiddleware(moderationHandler.Claim)))
+	r.Get("/moderation/queue", mdware.AuthMiddleware(mdware.AccessMiddleware(moderationHandler.GetQueue)))
+	r.Post("/moderation/renew", mdware.AuthMiddleware(mdware.AccessMiddleware(moderationHandler.Renew)))
 
 	fmt.Println("done")
 	err = http.ListenAndServe(":8081", r)
@@ -77,3 +153,38 @@ func Run(cfg *config.Config) {
 		fmt.Println(err)
 	}
 }
+
+func newAutoModerator(rulesFile string, declineReasons []domain.DeclineReason) (*usecase.AutoModerator, error) {
+	rulesCfg, err := config.ReadModerationRules(rulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]domain.AutoModerationRule, 0, len(rulesCfg.Rules))
+	for _, ruleCfg := range rulesCfg.Rules {
+		owners := make([]uuid.UUID, 0, len(ruleCfg.Owners))
+		for _, owner := range ruleCfg.Owners {
+			ownerID, err := uuid.Parse(owner)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: bad owner id %q: %v", ruleCfg.Name, owner, err.Error())
+			}
+			owners = append(owners, ownerID)
+		}
+
+		rules = append(rules, domain.AutoModerationRule{
+			Name:            ruleCfg.Name,
+			Kind:            ruleCfg.Kind,
+			Outcome:         ruleCfg.Outcome,
+			ReasonCode:      ruleCfg.ReasonCode,
+			MaxDeviation:    ruleCfg.MaxDeviation,
+			MinFlats:        ruleCfg.MinFlats,
+			Words:           ruleCfg.Words,
+			MaxRooms:        ruleCfg.MaxRooms,
+			MinPricePerRoom: ruleCfg.MinPricePerRoom,
+			MaxPricePerRoom: ruleCfg.MaxPricePerRoom,
+			Owners:          owners,
+		})
+	}
+
+	return usecase.NewAutoModerator(rules, declineReasons)
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"avito-test-task/internal/domain"
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+type DeveloperHandler struct {
+	uc        domain.DeveloperUsecase
+	lg        *zap.Logger
+	dbTimeout time.Duration
+}
+
+func NewDeveloperHandler(uc domain.DeveloperUsecase, timeout time.Duration, lg *zap.Logger) *DeveloperHandler {
+	return &DeveloperHandler{uc, lg, timeout}
+}
+
+func (h *DeveloperHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody          []byte
+		developerRequest  domain.CreateDeveloperRequest
+		developerResponse domain.DeveloperResponse
+	)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.lg.Warn("developer handler: create error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ReadHTTPBodyError, ReadHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+	err = json.Unmarshal(body, &developerRequest)
+	if err != nil {
+		h.lg.Warn("developer handler: create error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnmarshalHTTPBodyError, UnmarshalHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	developerResponse, err = h.uc.Create(ctx, &developerRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("developer handler: create error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), CreateDeveloperError, CreateDeveloperErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(developerResponse)
+	if err != nil {
+		h.lg.Warn("developer handler: create error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *DeveloperHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody           []byte
+		developersRequest  domain.DevelopersRequest
+		developersResponse domain.DevelopersResponse
+	)
+	defer r.Body.Close()
+
+	query := r.URL.Query()
+	err := parseIntQueryParams(query, map[string]*int{
+		"limit": &developersRequest.Limit,
+	})
+	if err != nil {
+		h.lg.Warn("developer handler: get all error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+	developersRequest.Cursor = query.Get("cursor")
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	developersResponse, err = h.uc.GetAll(ctx, &developersRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("developer handler: get all error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetDevelopersError, GetDevelopersErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(developersResponse)
+	if err != nil {
+		h.lg.Warn("developer handler: get all error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *DeveloperHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody          []byte
+		developerResponse domain.DeveloperResponse
+	)
+	defer r.Body.Close()
+
+	id, err := parsePathID(r.URL.Path, 0)
+	if err != nil {
+		h.lg.Warn("developer handler: get by id error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	developerResponse, err = h.uc.GetByID(ctx, id, h.lg)
+	if err != nil {
+		h.lg.Warn("developer handler: get by id error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetDeveloperError, GetDeveloperErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(developerResponse)
+	if err != nil {
+		h.lg.Warn("developer handler: get by id error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *DeveloperHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody          []byte
+		updateRequest     domain.UpdateDeveloperRequest
+		developerResponse domain.DeveloperResponse
+	)
+	defer r.Body.Close()
+
+	id, err := parsePathID(r.URL.Path, 0)
+	if err != nil {
+		h.lg.Warn("developer handler: update error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.lg.Warn("developer handler: update error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ReadHTTPBodyError, ReadHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+	err = json.Unmarshal(body, &updateRequest)
+	if err != nil {
+		h.lg.Warn("developer handler: update error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnmarshalHTTPBodyError, UnmarshalHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+	updateRequest.ID = id
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	developerResponse, err = h.uc.Update(ctx, &updateRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("developer handler: update error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UpdateDeveloperError, UpdateDeveloperErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(developerResponse)
+	if err != nil {
+		h.lg.Warn("developer handler: update error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *DeveloperHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody []byte
+	)
+	defer r.Body.Close()
+
+	id, err := parsePathID(r.URL.Path, 0)
+	if err != nil {
+		h.lg.Warn("developer handler: delete error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	err = h.uc.Delete(ctx, id, h.lg)
+	if err != nil {
+		h.lg.Warn("developer handler: delete error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), DeleteDeveloperError, DeleteDeveloperErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeveloperHandler) GetHouses(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody       []byte
+		housesRequest  domain.DeveloperHousesRequest
+		housesResponse domain.HouseSearchResponse
+	)
+	defer r.Body.Close()
+
+	id, err := parsePathID(r.URL.Path, 1)
+	if err != nil {
+		h.lg.Warn("developer handler: get houses error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+	housesRequest.ID = id
+
+	query := r.URL.Query()
+	err = parseIntQueryParams(query, map[string]*int{
+		"limit": &housesRequest.Limit,
+	})
+	if err != nil {
+		h.lg.Warn("developer handler: get houses error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+	housesRequest.Cursor = query.Get("cursor")
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	housesResponse, err = h.uc.GetHouses(ctx, &housesRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("developer handler: get houses error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetDeveloperHousesError, GetDeveloperHousesErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(housesResponse)
+	if err != nil {
+		h.lg.Warn("developer handler: get houses error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
@@ -36,24 +36,74 @@ const (
 	SubscribeOnHouseError
 	NoAccessError
 	ExtractRoleFromTokenError
+	SearchFlatsError
+	EditFlatError
+	GetFlatEditsError
+	ArchiveFlatError
+	RestoreFlatError
+	ClaimFlatError
+	GetModerationQueueError
+	RenewClaimError
+	GetFlatDetailError
+	GetFlatHistoryError
+	GetOwnFlatsError
+	SearchHousesError
+	EditHouseError
+	DeleteHouseError
+	CreateDeveloperError
+	GetDevelopersError
+	GetDeveloperError
+	UpdateDeveloperError
+	DeleteDeveloperError
+	GetDeveloperHousesError
+	SearchNearbyHousesError
+	SearchHousesByAddressError
+	GetHouseStatsError
+	UnsubscribeFromHouseError
+	GetSubscriptionsError
 )
 
 const (
-	ReadHTTPBodyMsg              = "can't read request"
-	UnmarshalHTTPBodyMsg         = "can't unmarshal request"
-	CreateHouseErrorMsg          = "can't create house"
-	MarshalHTTPBodyErrorMsg      = "can't marshal response"
-	ParseURLErrorMsg             = "can't parse url"
-	GetFlatsByHouseIDErrorMsg    = "can't get flats by house id"
-	NotAuthorizedErrorMsg        = "not authorized"
-	RegisterUserErrorMsg         = "can't register user"
-	LoginUserErrorMsg            = "can't login user"
-	DummyLoginErrorMsg           = "can't simple login"
-	CreateFlatErrorMsg           = "can't create flat"
-	UpdateFlatErrorMsg           = "can't update flat"
-	SubscribeOnHouseErrorMsg     = "can't subscribe on house"
-	NoAccessErrorMsg             = "no enough access rights"
-	ExtractRoleFromTokenErrorMsg = "can't extract role"
+	ReadHTTPBodyMsg               = "can't read request"
+	UnmarshalHTTPBodyMsg          = "can't unmarshal request"
+	CreateHouseErrorMsg           = "can't create house"
+	MarshalHTTPBodyErrorMsg       = "can't marshal response"
+	ParseURLErrorMsg              = "can't parse url"
+	GetFlatsByHouseIDErrorMsg     = "can't get flats by house id"
+	NotAuthorizedErrorMsg         = "not authorized"
+	RegisterUserErrorMsg          = "can't register user"
+	LoginUserErrorMsg             = "can't login user"
+	DummyLoginErrorMsg            = "can't simple login"
+	CreateFlatErrorMsg            = "can't create flat"
+	UpdateFlatErrorMsg            = "can't update flat"
+	SubscribeOnHouseErrorMsg      = "can't subscribe on house"
+	NoAccessErrorMsg              = "no enough access rights"
+	ExtractRoleFromTokenErrorMsg  = "can't extract role"
+	SearchFlatsErrorMsg           = "can't search flats"
+	EditFlatErrorMsg              = "can't edit flat"
+	GetFlatEditsErrorMsg          = "can't get flat edits"
+	ArchiveFlatErrorMsg           = "can't archive flat"
+	RestoreFlatErrorMsg           = "can't restore flat"
+	ClaimFlatErrorMsg             = "can't claim flat for moderation"
+	GetModerationQueueErrorMsg    = "can't get moderation queue"
+	RenewClaimErrorMsg            = "can't renew moderation claim"
+	GetFlatDetailErrorMsg         = "can't get flat"
+	GetFlatHistoryErrorMsg        = "can't get flat status history"
+	GetOwnFlatsErrorMsg           = "can't get own flats"
+	SearchHousesErrorMsg          = "can't search houses"
+	EditHouseErrorMsg             = "can't edit house"
+	DeleteHouseErrorMsg           = "can't delete house"
+	CreateDeveloperErrorMsg       = "can't create developer"
+	GetDevelopersErrorMsg         = "can't get developers"
+	GetDeveloperErrorMsg          = "can't get developer"
+	UpdateDeveloperErrorMsg       = "can't update developer"
+	DeleteDeveloperErrorMsg       = "can't delete developer"
+	GetDeveloperHousesErrorMsg    = "can't get developer houses"
+	SearchNearbyHousesErrorMsg    = "can't search nearby houses"
+	SearchHousesByAddressErrorMsg = "can't search houses by address"
+	GetHouseStatsErrorMsg         = "can't get house stats"
+	UnsubscribeFromHouseErrorMsg  = "can't unsubscribe from house"
+	GetSubscriptionsErrorMsg      = "can't get subscriptions"
 )
 
 func CreateErrorResponse(ctx context.Context, errCode int, msg string) []byte {
@@ -86,13 +136,86 @@ func GetReturnHTTPCode(w http.ResponseWriter, err error) int {
 		domain.ErrFlat_BadNewFlat,
 		domain.ErrFlat_BadStatus,
 		domain.ErrFlat_BadRequest,
+		domain.ErrFlat_BadFilter,
+		domain.ErrFlat_BadSort,
+		domain.ErrFlat_BadLimit,
+		domain.ErrFlat_BadCursor,
+		domain.ErrFlat_BadArea,
+		domain.ErrFlat_BadFloor,
+		domain.ErrFlat_BadDescription,
+		domain.ErrFlat_BadLayout,
+		domain.ErrFlat_BadBalcony,
+		domain.ErrModeration_BadLimit,
+		domain.ErrFlat_BadReason,
+		domain.ErrFlat_BadComment,
+		domain.ErrHouse_BadFilter,
+		domain.ErrHouse_BadLimit,
+		domain.ErrHouse_BadCursor,
+		domain.ErrHouse_BadEdit,
+		domain.ErrDeveloper_BadRequest,
+		domain.ErrDeveloper_BadID,
+		domain.ErrDeveloper_BadName,
+		domain.ErrDeveloper_BadLimit,
+		domain.ErrDeveloper_BadCursor,
+		domain.ErrHouse_BadLocation,
+		domain.ErrHouse_BadRadius,
+		domain.ErrHouse_BadQuery,
+		domain.ErrSubscription_BadToken,
+		domain.ErrVersion_BadIfMatch,
 	}
 
+	notFoundErrorsList := []error{
+		domain.ErrFlat_NotFound,
+		domain.ErrHouse_NotFound,
+		domain.ErrModeration_EmptyQueue,
+		domain.ErrDeveloper_NotFound,
+		domain.ErrSubscription_NotFound,
+	}
+
+	forbiddenErrorsList := []error{
+		domain.ErrFlat_NotOwner,
+	}
+
+	conflictErrorsList := []error{
+		domain.ErrFlat_OnModeration,
+		domain.ErrFlat_Conflict,
+		domain.ErrFlat_Archived,
+		domain.ErrFlat_NotArchived,
+		domain.ErrFlat_NumberTaken,
+		domain.ErrFlat_BadTransition,
+		domain.ErrModeration_NotClaimed,
+		domain.ErrHouse_HasFlats,
+		domain.ErrHouse_Conflict,
+		domain.ErrDeveloper_Exists,
+		domain.ErrDeveloper_HasHouses,
+	}
+
+	preconditionErrorsList := []error{
+		domain.ErrFlat_VersionMismatch,
+		domain.ErrHouse_VersionMismatch,
+	}
+
+	switch {
+	case isOneOf(err, errorsList):
+		return http.StatusBadRequest
+	case isOneOf(err, notFoundErrorsList):
+		return http.StatusNotFound
+	case isOneOf(err, forbiddenErrorsList):
+		return http.StatusForbidden
+	case isOneOf(err, conflictErrorsList):
+		return http.StatusConflict
+	case isOneOf(err, preconditionErrorsList):
+		return http.StatusPreconditionFailed
+	}
+	w.Header().Set("Retry-After", "120")
+	return http.StatusInternalServerError
+}
+
+func isOneOf(err error, errorsList []error) bool {
 	for _, e := range errorsList {
 		if errors.Is(err, e) {
-			return http.StatusBadRequest
+			return true
 		}
 	}
-	w.Header().Set("Retry-After", "120")
-	return http.StatusInternalServerError
+	return false
 }
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"avito-test-task/internal/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ifMatchVersion reads the version from the If-Match header; 0 means the header is absent or "*".
+func ifMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("bad If-Match header %q: %w", header, domain.ErrVersion_BadIfMatch)
+	}
+	return version, nil
+}
+
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// flatListETag identifies a flat list of a house by the time of its last flat change and by the statuses
+// the caller can see, so clients and moderators never share a validator.
+func flatListETag(updateDate time.Time, status string) string {
+	return `W/"` + strconv.FormatInt(updateDate.UnixMicro(), 36) + "-" + status + `"`
+}
+
+// setListValidators makes the list cacheable only by the caller and only after revalidation.
+func setListValidators(w http.ResponseWriter, etag string, updateDate time.Time) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", updateDate.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Vary", "Authorization")
+}
+
+// notModified evaluates If-None-Match and, when it is absent, If-Modified-Since.
+// Last-Modified has whole seconds, so the date is compared truncated.
+func notModified(r *http.Request, etag string, updateDate time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !updateDate.Truncate(time.Second).After(since)
+}
@@ -89,6 +89,7 @@ func (h *FlatHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, flatResponse.Version)
 	w.Write(respBody)
 }
 
@@ -119,6 +120,15 @@ func (h *FlatHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	flatRequest.Version, err = ifMatchVersion(r)
+	if err != nil {
+		h.lg.Warn("flat handler: update error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UpdateFlatError, UpdateFlatErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
 	userID, err := pkg.ExtractPayloadFromToken(r.Header.Get("authorization"), "userID")
 	if err != nil {
 		h.lg.Warn("flat handler: create error", zap.Error(err))
@@ -157,5 +167,415 @@ func (h *FlatHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, flatResponse.Version)
+	w.Write(respBody)
+}
+
+func (h *FlatHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody       []byte
+		searchRequest  domain.FlatSearchRequest
+		searchResponse domain.FlatSearchResponse
+	)
+	defer r.Body.Close()
+
+	query := r.URL.Query()
+	err := parseIntQueryParams(query, map[string]*int{
+		"price_min": &searchRequest.PriceMin,
+		"price_max": &searchRequest.PriceMax,
+		"rooms":     &searchRequest.Rooms,
+		"house_id":  &searchRequest.HouseID,
+		"year_min":  &searchRequest.YearMin,
+		"year_max":  &searchRequest.YearMax,
+		"limit":     &searchRequest.Limit,
+	})
+	if err != nil {
+		h.lg.Warn("flat handler: search error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+	searchRequest.Developer = query.Get("developer")
+	searchRequest.Sort = query.Get("sort")
+	searchRequest.Cursor = query.Get("cursor")
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	searchResponse, err = h.uc.Search(ctx, &searchRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("flat handler: search error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), SearchFlatsError, SearchFlatsErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(searchResponse)
+	if err != nil {
+		h.lg.Warn("flat handler: search error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *FlatHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody     []byte
+		editRequest  domain.EditFlatRequest
+		flatResponse domain.CreateFlatResponse
+	)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.lg.Warn("flat handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ReadHTTPBodyError, ReadHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+	err = json.Unmarshal(body, &editRequest)
+	if err != nil {
+		h.lg.Warn("flat handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnmarshalHTTPBodyError, UnmarshalHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	editRequest.Version, err = ifMatchVersion(r)
+	if err != nil {
+		h.lg.Warn("flat handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), EditFlatError, EditFlatErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	userUuid, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn("flat handler: edit error: extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), EditFlatError, EditFlatErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	flatResponse, err = h.uc.Edit(ctx, userUuid, &editRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("flat handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), EditFlatError, EditFlatErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(flatResponse)
+	if err != nil {
+		h.lg.Warn("flat handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	setETag(w, flatResponse.Version)
+	w.Write(respBody)
+}
+
+func (h *FlatHandler) GetEdits(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody      []byte
+		editsResponse domain.FlatEditsResponse
+	)
+	defer r.Body.Close()
+
+	houseID, flatID, err := parseFlatPath(r.URL.Path, 1)
+	if err != nil {
+		h.lg.Warn("flat handler: get edits error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	editsResponse, err = h.uc.GetEdits(ctx, flatID, houseID, h.lg)
+	if err != nil {
+		h.lg.Warn("flat handler: get edits error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetFlatEditsError, GetFlatEditsErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(editsResponse)
+	if err != nil {
+		h.lg.Warn("flat handler: get edits error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *FlatHandler) GetDetail(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody       []byte
+		detailResponse domain.FlatDetailResponse
+	)
+	defer r.Body.Close()
+
+	houseID, flatID, err := parseFlatPath(r.URL.Path, 0)
+	if err != nil {
+		h.lg.Warn("flat handler: get detail error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	userUuid, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn("flat handler: get detail error: extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetFlatDetailError, GetFlatDetailErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	role, err := extractRole(r)
+	if err != nil {
+		h.lg.Warn("flat handler: get detail error: extract role", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ExtractRoleFromTokenError, ExtractRoleFromTokenErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	detailResponse, err = h.uc.GetDetail(ctx, userUuid, role, flatID, houseID, h.lg)
+	if err != nil {
+		h.lg.Warn("flat handler: get detail error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetFlatDetailError, GetFlatDetailErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(detailResponse)
+	if err != nil {
+		h.lg.Warn("flat handler: get detail error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	setETag(w, detailResponse.Version)
+	w.Write(respBody)
+}
+
+func (h *FlatHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody        []byte
+		historyResponse domain.FlatHistoryResponse
+	)
+	defer r.Body.Close()
+
+	houseID, flatID, err := parseFlatPath(r.URL.Path, 1)
+	if err != nil {
+		h.lg.Warn("flat handler: get history error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	userUuid, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn("flat handler: get history error: extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetFlatHistoryError, GetFlatHistoryErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	role, err := extractRole(r)
+	if err != nil {
+		h.lg.Warn("flat handler: get history error: extract role", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ExtractRoleFromTokenError, ExtractRoleFromTokenErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	historyResponse, err = h.uc.GetHistory(ctx, userUuid, role, flatID, houseID, h.lg)
+	if err != nil {
+		h.lg.Warn("flat handler: get history error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetFlatHistoryError, GetFlatHistoryErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(historyResponse)
+	if err != nil {
+		h.lg.Warn("flat handler: get history error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *FlatHandler) GetOwnFlats(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody      []byte
+		flatsResponse domain.OwnerFlatsResponse
+	)
+	defer r.Body.Close()
+
+	userUuid, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn("flat handler: get own flats error: extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetOwnFlatsError, GetOwnFlatsErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	flatsResponse, err = h.uc.GetOwnFlats(ctx, userUuid, h.lg)
+	if err != nil {
+		h.lg.Warn("flat handler: get own flats error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetOwnFlatsError, GetOwnFlatsErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(flatsResponse)
+	if err != nil {
+		h.lg.Warn("flat handler: get own flats error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+type archiveAction func(ctx context.Context, userID uuid.UUID, role string,
+	req *domain.ArchiveFlatRequest, lg *zap.Logger) (domain.CreateFlatResponse, error)
+
+func (h *FlatHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.changeArchiveState(w, r, h.uc.Archive, ArchiveFlatError, ArchiveFlatErrorMsg, "archive")
+}
+
+func (h *FlatHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	h.changeArchiveState(w, r, h.uc.Restore, RestoreFlatError, RestoreFlatErrorMsg, "restore")
+}
+
+func (h *FlatHandler) changeArchiveState(w http.ResponseWriter, r *http.Request, action archiveAction,
+	errCode int, errMsg string, actionName string) {
+	var (
+		respBody       []byte
+		archiveRequest domain.ArchiveFlatRequest
+		flatResponse   domain.CreateFlatResponse
+	)
+	defer r.Body.Close()
+
+	logMsg := "flat handler: " + actionName + " error"
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.lg.Warn(logMsg, zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ReadHTTPBodyError, ReadHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+	err = json.Unmarshal(body, &archiveRequest)
+	if err != nil {
+		h.lg.Warn(logMsg, zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnmarshalHTTPBodyError, UnmarshalHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	archiveRequest.Version, err = ifMatchVersion(r)
+	if err != nil {
+		h.lg.Warn(logMsg, zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), errCode, errMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	userUuid, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn(logMsg+": extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), errCode, errMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	role, err := extractRole(r)
+	if err != nil {
+		h.lg.Warn(logMsg+": extract role", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ExtractRoleFromTokenError, ExtractRoleFromTokenErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	flatResponse, err = action(ctx, userUuid, role, &archiveRequest, h.lg)
+	if err != nil {
+		h.lg.Warn(logMsg, zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), errCode, errMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(flatResponse)
+	if err != nil {
+		h.lg.Warn(logMsg, zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	setETag(w, flatResponse.Version)
 	w.Write(respBody)
 }
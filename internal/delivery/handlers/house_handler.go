@@ -5,6 +5,7 @@ import (
 	"avito-test-task/pkg"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"io"
@@ -73,9 +74,122 @@ func (h *HouseHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, houseResponse.Version)
 	w.Write(respBody)
 }
 
+func (h *HouseHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody      []byte
+		editRequest   domain.EditHouseRequest
+		houseResponse domain.CreateHouseResponse
+	)
+	defer r.Body.Close()
+
+	pathParts := strings.Split(r.URL.Path, "/")
+	id, err := strconv.Atoi(pathParts[len(pathParts)-1])
+	if err != nil {
+		h.lg.Warn("house handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.lg.Warn("house handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ReadHTTPBodyError, ReadHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+	err = json.Unmarshal(body, &editRequest)
+	if err != nil {
+		h.lg.Warn("house handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnmarshalHTTPBodyError, UnmarshalHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+	editRequest.ID = id
+
+	editRequest.Version, err = ifMatchVersion(r)
+	if err != nil {
+		h.lg.Warn("house handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), EditHouseError, EditHouseErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	houseResponse, err = h.uc.Edit(ctx, &editRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), EditHouseError, EditHouseErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(houseResponse)
+	if err != nil {
+		h.lg.Warn("house handler: edit error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	setETag(w, houseResponse.Version)
+	w.Write(respBody)
+}
+
+func (h *HouseHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody      []byte
+		deleteRequest domain.DeleteHouseRequest
+	)
+	defer r.Body.Close()
+
+	pathParts := strings.Split(r.URL.Path, "/")
+	id, err := strconv.Atoi(pathParts[len(pathParts)-1])
+	if err != nil {
+		h.lg.Warn("house handler: delete error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+	deleteRequest.ID = id
+
+	deleteRequest.Version, err = ifMatchVersion(r)
+	if err != nil {
+		h.lg.Warn("house handler: delete error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), DeleteHouseError, DeleteHouseErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	err = h.uc.Delete(ctx, &deleteRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: delete error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), DeleteHouseError, DeleteHouseErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *HouseHandler) GetFlatsByID(w http.ResponseWriter, r *http.Request) {
 	var (
 		respBody []byte
@@ -93,6 +207,24 @@ func (h *HouseHandler) GetFlatsByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	flatsRequest := domain.FlatsByHouseRequest{ID: id}
+	query := r.URL.Query()
+	err = parseIntQueryParams(query, map[string]*int{
+		"rooms":     &flatsRequest.Rooms,
+		"price_min": &flatsRequest.PriceMin,
+		"price_max": &flatsRequest.PriceMax,
+		"limit":     &flatsRequest.Limit,
+	})
+	if err != nil {
+		h.lg.Warn("house handler: get flats by id error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+	flatsRequest.Sort = query.Get("sort")
+	flatsRequest.Cursor = query.Get("cursor")
+
 	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
 	defer cancel()
 
@@ -112,7 +244,24 @@ func (h *HouseHandler) GetFlatsByID(w http.ResponseWriter, r *http.Request) {
 		status = domain.ApprovedStatus
 	}
 
-	flats, err := h.uc.GetFlatsByHouseID(ctx, id, status, h.lg)
+	// the date is read before the flats: a change in between only makes the validator older than the body
+	updateDate, err := h.uc.GetFlatsUpdateDate(ctx, id, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: get flats by id error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetFlatsByHouseIDError, GetFlatsByHouseIDErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	etag := flatListETag(updateDate, status)
+	if notModified(r, etag, updateDate) {
+		setListValidators(w, etag, updateDate)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	flats, err := h.uc.GetFlatsByHouseID(ctx, &flatsRequest, status, h.lg)
 	if err != nil {
 		h.lg.Warn("house handler: get flats by id error", zap.Error(err))
 		respBody = CreateErrorResponse(r.Context(), GetFlatsByHouseIDError, GetFlatsByHouseIDErrorMsg)
@@ -130,6 +279,7 @@ func (h *HouseHandler) GetFlatsByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setListValidators(w, etag, updateDate)
 	w.Write(respBody)
 }
 
@@ -183,3 +333,330 @@ func (h *HouseHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+func (h *HouseHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody []byte
+	)
+	defer r.Body.Close()
+
+	userUuid, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn("house handler: unsubscribe error: extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnsubscribeFromHouseError, UnsubscribeFromHouseErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	id, err := parsePathID(r.URL.Path, 1)
+	if err != nil {
+		h.lg.Warn("house handler: unsubscribe error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	err = h.uc.UnsubscribeByID(ctx, id, userUuid, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: unsubscribe error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnsubscribeFromHouseError, UnsubscribeFromHouseErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CheckUnsubscribeToken answers GET on the link from a notification without unsubscribing:
+// mail scanners and prefetchers follow every link, so only POST changes the subscription.
+func (h *HouseHandler) CheckUnsubscribeToken(w http.ResponseWriter, r *http.Request) {
+	h.handleUnsubscribeToken(w, r, h.uc.CheckUnsubscribeToken)
+}
+
+// UnsubscribeByToken answers POST on the link, including the RFC 8058 one-click request of mail clients.
+// The token in the link stands in for the authorization header.
+func (h *HouseHandler) UnsubscribeByToken(w http.ResponseWriter, r *http.Request) {
+	h.handleUnsubscribeToken(w, r, h.uc.UnsubscribeByToken)
+}
+
+func (h *HouseHandler) handleUnsubscribeToken(w http.ResponseWriter, r *http.Request,
+	action func(ctx context.Context, token string, lg *zap.Logger) (domain.UnsubscribeResponse, error)) {
+	var (
+		respBody            []byte
+		unsubscribeResponse domain.UnsubscribeResponse
+	)
+	defer r.Body.Close()
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.lg.Warn("house handler: unsubscribe by token error: no token")
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	unsubscribeResponse, err := action(ctx, token, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: unsubscribe by token error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnsubscribeFromHouseError, UnsubscribeFromHouseErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(unsubscribeResponse)
+	if err != nil {
+		h.lg.Warn("house handler: unsubscribe by token error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *HouseHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody              []byte
+		subscriptionsResponse domain.SubscriptionsResponse
+	)
+	defer r.Body.Close()
+
+	userUuid, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn("house handler: get subscriptions error: extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetSubscriptionsError, GetSubscriptionsErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	subscriptionsResponse, err = h.uc.GetSubscriptions(ctx, userUuid, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: get subscriptions error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetSubscriptionsError, GetSubscriptionsErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(subscriptionsResponse)
+	if err != nil {
+		h.lg.Warn("house handler: get subscriptions error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *HouseHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody       []byte
+		searchRequest  domain.HouseSearchRequest
+		searchResponse domain.HouseSearchResponse
+	)
+	defer r.Body.Close()
+
+	query := r.URL.Query()
+	err := parseIntQueryParams(query, map[string]*int{
+		"developer_id": &searchRequest.DeveloperID,
+		"year_min":     &searchRequest.YearMin,
+		"year_max":     &searchRequest.YearMax,
+		"limit":        &searchRequest.Limit,
+	})
+	if err == nil && query.Get("has_approved_flats") != "" {
+		searchRequest.HasApprovedFlats, err = strconv.ParseBool(query.Get("has_approved_flats"))
+	}
+	if err == nil {
+		searchRequest.Box, err = parseGeoBox(query)
+	}
+	if err != nil {
+		h.lg.Warn("house handler: search error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+	searchRequest.Developer = query.Get("developer")
+	searchRequest.Address = query.Get("address")
+	searchRequest.Cursor = query.Get("cursor")
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	searchResponse, err = h.uc.Search(ctx, &searchRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: search error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), SearchHousesError, SearchHousesErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(searchResponse)
+	if err != nil {
+		h.lg.Warn("house handler: search error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *HouseHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody      []byte
+		statsResponse domain.HouseStatsResponse
+	)
+	defer r.Body.Close()
+
+	id, err := parsePathID(r.URL.Path, 1)
+	if err != nil {
+		h.lg.Warn("house handler: get stats error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	statsResponse, err = h.uc.GetStats(ctx, id, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: get stats error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetHouseStatsError, GetHouseStatsErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(statsResponse)
+	if err != nil {
+		h.lg.Warn("house handler: get stats error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *HouseHandler) Nearby(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody       []byte
+		nearbyRequest  domain.HouseNearbyRequest
+		nearbyResponse domain.HouseNearbyResponse
+	)
+	defer r.Body.Close()
+
+	query := r.URL.Query()
+	err := parseFloatQueryParams(query, map[string]*float64{
+		"lat":    &nearbyRequest.Latitude,
+		"lon":    &nearbyRequest.Longitude,
+		"radius": &nearbyRequest.Radius,
+	})
+	if err == nil {
+		err = parseIntQueryParams(query, map[string]*int{
+			"limit": &nearbyRequest.Limit,
+		})
+	}
+	if err == nil && (query.Get("lat") == "" || query.Get("lon") == "") {
+		err = errors.New("lat and lon are required")
+	}
+	if err != nil {
+		h.lg.Warn("house handler: nearby error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	nearbyResponse, err = h.uc.Nearby(ctx, &nearbyRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: nearby error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), SearchNearbyHousesError, SearchNearbyHousesErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(nearbyResponse)
+	if err != nil {
+		h.lg.Warn("house handler: nearby error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *HouseHandler) SearchByAddress(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody       []byte
+		searchRequest  domain.HouseAddressSearchRequest
+		searchResponse domain.HouseAddressSearchResponse
+	)
+	defer r.Body.Close()
+
+	query := r.URL.Query()
+	err := parseIntQueryParams(query, map[string]*int{
+		"limit": &searchRequest.Limit,
+	})
+	if err != nil {
+		h.lg.Warn("house handler: search by address error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+	searchRequest.Query = query.Get("q")
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	searchResponse, err = h.uc.SearchByAddress(ctx, &searchRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("house handler: search by address error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), SearchHousesByAddressError, SearchHousesByAddressErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(searchResponse)
+	if err != nil {
+		h.lg.Warn("house handler: search by address error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"avito-test-task/internal/domain"
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+type ModerationHandler struct {
+	uc        domain.ModerationUsecase
+	lg        *zap.Logger
+	dbTimeout time.Duration
+}
+
+func NewModerationHandler(uc domain.ModerationUsecase, timeout time.Duration, lg *zap.Logger) *ModerationHandler {
+	return &ModerationHandler{
+		uc:        uc,
+		lg:        lg,
+		dbTimeout: timeout,
+	}
+}
+
+func (h *ModerationHandler) Claim(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody     []byte
+		flatResponse domain.CreateFlatResponse
+	)
+	defer r.Body.Close()
+
+	moderatorID, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn("moderation handler: claim error: extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ClaimFlatError, ClaimFlatErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	flatResponse, err = h.uc.Claim(ctx, moderatorID, h.lg)
+	if err != nil {
+		h.lg.Warn("moderation handler: claim error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ClaimFlatError, ClaimFlatErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(flatResponse)
+	if err != nil {
+		h.lg.Warn("moderation handler: claim error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	setETag(w, flatResponse.Version)
+	w.Write(respBody)
+}
+
+func (h *ModerationHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody      []byte
+		limit         int
+		queueResponse domain.ModerationQueueResponse
+	)
+	defer r.Body.Close()
+
+	err := parseIntQueryParams(r.URL.Query(), map[string]*int{
+		"limit": &limit,
+	})
+	if err != nil {
+		h.lg.Warn("moderation handler: get queue error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ParseURLError, ParseURLErrorMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	queueResponse, err = h.uc.GetQueue(ctx, limit, h.lg)
+	if err != nil {
+		h.lg.Warn("moderation handler: get queue error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), GetModerationQueueError, GetModerationQueueErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(queueResponse)
+	if err != nil {
+		h.lg.Warn("moderation handler: get queue error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	w.Write(respBody)
+}
+
+func (h *ModerationHandler) Renew(w http.ResponseWriter, r *http.Request) {
+	var (
+		respBody     []byte
+		renewRequest domain.RenewClaimRequest
+		flatResponse domain.CreateFlatResponse
+	)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.lg.Warn("moderation handler: renew error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), ReadHTTPBodyError, ReadHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+	err = json.Unmarshal(body, &renewRequest)
+	if err != nil {
+		h.lg.Warn("moderation handler: renew error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), UnmarshalHTTPBodyError, UnmarshalHTTPBodyMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	moderatorID, err := extractUserID(r)
+	if err != nil {
+		h.lg.Warn("moderation handler: renew error: extract id", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), RenewClaimError, RenewClaimErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.dbTimeout*time.Second)
+	defer cancel()
+
+	flatResponse, err = h.uc.Renew(ctx, moderatorID, &renewRequest, h.lg)
+	if err != nil {
+		h.lg.Warn("moderation handler: renew error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), RenewClaimError, RenewClaimErrorMsg)
+		w.WriteHeader(GetReturnHTTPCode(w, err))
+		w.Write(respBody)
+		return
+	}
+
+	respBody, err = json.Marshal(flatResponse)
+	if err != nil {
+		h.lg.Warn("moderation handler: renew error", zap.Error(err))
+		respBody = CreateErrorResponse(r.Context(), MarshalHTTPBodyError, MarshalHTTPBodyErrorMsg)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(respBody)
+		return
+	}
+
+	setETag(w, flatResponse.Version)
+	w.Write(respBody)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"avito-test-task/internal/domain"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func parseIntQueryParams(query url.Values, params map[string]*int) error {
+	for key, value := range params {
+		raw := query.Get(key)
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		*value = parsed
+	}
+
+	return nil
+}
+
+func parseFlatPath(path string, suffixParts int) (int, int, error) {
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(pathParts) < suffixParts+2 {
+		return 0, 0, errors.New("bad flat path")
+	}
+
+	houseID, err := strconv.Atoi(pathParts[len(pathParts)-suffixParts-2])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	flatID, err := strconv.Atoi(pathParts[len(pathParts)-suffixParts-1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return houseID, flatID, nil
+}
+
+// parsePathID reads the numeric id that is followed by suffixParts segments, as in /developers/{id}/houses.
+func parsePathID(path string, suffixParts int) (int, error) {
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(pathParts) < suffixParts+2 {
+		return 0, errors.New("bad id path")
+	}
+
+	return strconv.Atoi(pathParts[len(pathParts)-suffixParts-1])
+}
+
+func parseFloatQueryParams(query url.Values, params map[string]*float64) error {
+	for key, value := range params {
+		raw := query.Get(key)
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		*value = parsed
+	}
+
+	return nil
+}
+
+// parseGeoBox reads min_lat, max_lat, min_lon and max_lon; the box is nil when none of them is set.
+func parseGeoBox(query url.Values) (*domain.GeoBox, error) {
+	keys := []string{"min_lat", "max_lat", "min_lon", "max_lon"}
+	present := 0
+	for _, key := range keys {
+		if query.Get(key) != "" {
+			present++
+		}
+	}
+	if present == 0 {
+		return nil, nil
+	}
+	if present != len(keys) {
+		return nil, errors.New("bounding box needs min_lat, max_lat, min_lon and max_lon")
+	}
+
+	var box domain.GeoBox
+	err := parseFloatQueryParams(query, map[string]*float64{
+		"min_lat": &box.MinLat,
+		"max_lat": &box.MaxLat,
+		"min_lon": &box.MinLon,
+		"max_lon": &box.MaxLon,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &box, nil
+}
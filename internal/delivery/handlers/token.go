@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"avito-test-task/pkg"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+func extractUserID(r *http.Request) (uuid.UUID, error) {
+	userID, err := pkg.ExtractPayloadFromToken(r.Header.Get("authorization"), "userID")
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return uuid.Parse(userID)
+}
+
+func extractRole(r *http.Request) (string, error) {
+	return pkg.ExtractPayloadFromToken(r.Header.Get("authorization"), "role")
+}
@@ -6,10 +6,18 @@ import (
 	"avito-test-task/pkg"
 	"net/http"
 	"regexp"
+	"strings"
 )
 
-func isModeratorOnly(path string) bool {
-	return path == "/house/create" || path == "/flat/update"
+func isModeratorOnly(method string, path string) bool {
+	matched, _ := regexp.MatchString("^/flat/[0-9]+/[0-9]+/edits$", path)
+	// reading houses and developers is open to everyone, changing or removing them is not
+	houseChange, _ := regexp.MatchString("^/house/[0-9]+$", path)
+	houseChange = houseChange && (method == http.MethodPatch || method == http.MethodDelete)
+	developerChange, _ := regexp.MatchString("^/developers(/[0-9]+)?$", path)
+	developerChange = developerChange && method != http.MethodGet
+	return path == "/house/create" || path == "/flat/update" || matched || houseChange || developerChange ||
+		strings.HasPrefix(path, "/moderation/")
 }
 
 func isClientOnly(path string) bool {
@@ -30,7 +38,7 @@ func AccessMiddleware(handler http.HandlerFunc) http.HandlerFunc {
 		}
 
 		path := r.URL.Path
-		if isModeratorOnly(path) && role != domain.Moderator {
+		if isModeratorOnly(r.Method, path) && role != domain.Moderator {
 			respBody = handlers.CreateErrorResponse(r.Context(), handlers.NoAccessError, handlers.NoAccessErrorMsg)
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write(respBody)
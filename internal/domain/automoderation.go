@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAutoModeration_BadKind    = errors.New("unknown auto-moderation rule kind")
+	ErrAutoModeration_BadOutcome = errors.New("unknown auto-moderation rule outcome")
+	ErrAutoModeration_BadReason  = errors.New("auto-moderation decline rule needs a known reason code")
+)
+
+const (
+	AutoApproveOutcome = "approve"
+	AutoDeclineOutcome = "decline"
+	AutoFlagOutcome    = "flag"
+)
+
+const (
+	PriceOutlierRule = "price_outlier"
+	BannedWordsRule  = "banned_words"
+	RoomsPriceRule   = "rooms_price"
+	TrustedOwnerRule = "trusted_owner"
+)
+
+// AutoModerationRule is one entry of the rules file. Only the parameters of its Kind are used.
+type AutoModerationRule struct {
+	Name       string
+	Kind       string
+	Outcome    string
+	ReasonCode string
+
+	MaxDeviation float64
+	MinFlats     int
+
+	Words []string
+
+	MaxRooms        int
+	MinPricePerRoom int
+	MaxPricePerRoom int
+
+	Owners []uuid.UUID
+}
+
+type HousePriceStats struct {
+	MedianPrice float64
+	Flats       int
+}
+
+// AutoModerationVerdict is the outcome of the first rule that fired for a new flat.
+type AutoModerationVerdict struct {
+	Rule       string
+	Outcome    string
+	ReasonCode string
+	Comment    string
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrDeveloper_BadRequest = errors.New("bad developer request")
+	ErrDeveloper_BadID      = errors.New("bad developer id")
+	ErrDeveloper_BadName    = errors.New("bad developer name")
+	ErrDeveloper_BadLimit   = errors.New("bad developers limit")
+	ErrDeveloper_BadCursor  = errors.New("bad developers cursor")
+	ErrDeveloper_NotFound   = errors.New("developer not found")
+	ErrDeveloper_Exists     = errors.New("developer already exists")
+	ErrDeveloper_HasHouses  = errors.New("developer still has houses")
+)
+
+const MaxDeveloperNameLength = 200
+
+type Developer struct {
+	DeveloperID         int
+	Name                string
+	CreateDeveloperDate time.Time
+}
+
+type CreateDeveloperRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateDeveloperRequest struct {
+	ID   int    `json:"-"`
+	Name string `json:"name"`
+}
+
+type DeveloperResponse struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+type DevelopersRequest struct {
+	Limit  int
+	Cursor string
+}
+
+type DevelopersCursor struct {
+	Name        string `json:"name"`
+	DeveloperID int    `json:"developer_id"`
+}
+
+type DevelopersFilter struct {
+	Limit int
+	After *DevelopersCursor
+}
+
+type DevelopersResponse struct {
+	Developers []DeveloperResponse `json:"developers"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type DeveloperHousesRequest struct {
+	ID     int
+	Limit  int
+	Cursor string
+}
+
+type DeveloperUsecase interface {
+	Create(ctx context.Context, req *CreateDeveloperRequest, lg *zap.Logger) (DeveloperResponse, error)
+	GetByID(ctx context.Context, id int, lg *zap.Logger) (DeveloperResponse, error)
+	GetAll(ctx context.Context, req *DevelopersRequest, lg *zap.Logger) (DevelopersResponse, error)
+	Update(ctx context.Context, req *UpdateDeveloperRequest, lg *zap.Logger) (DeveloperResponse, error)
+	Delete(ctx context.Context, id int, lg *zap.Logger) error
+	GetHouses(ctx context.Context, req *DeveloperHousesRequest, lg *zap.Logger) (HouseSearchResponse, error)
+}
+
+type DeveloperRepo interface {
+	Create(ctx context.Context, developer *Developer, lg *zap.Logger) (Developer, error)
+	GetByID(ctx context.Context, id int, lg *zap.Logger) (Developer, error)
+	GetAll(ctx context.Context, filter *DevelopersFilter, lg *zap.Logger) ([]Developer, error)
+	Update(ctx context.Context, developer *Developer, lg *zap.Logger) (Developer, error)
+	DeleteByID(ctx context.Context, id int, lg *zap.Logger) error
+}
@@ -3,8 +3,10 @@ package domain
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"time"
 )
 
 const (
@@ -12,9 +14,44 @@ const (
 	ApprovedStatus   = "approved"
 	DeclinedStatus   = "declined"
 	ModeratingStatus = "on moderation"
+	ArchivedStatus   = "archived"
 	AnyStatus        = "any"
 )
 
+const (
+	SortByPrice = "price"
+	SortByDate  = "date"
+	SortByRooms = "rooms"
+	SortByID    = "id"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+const (
+	StudioLayout   = "studio"
+	IsolatedLayout = "isolated"
+	AdjacentLayout = "adjacent"
+	FreeLayout     = "free"
+)
+
+const (
+	NoBalcony      = "none"
+	BalconyBalcony = "balcony"
+	LoggiaBalcony  = "loggia"
+)
+
+const MaxDescriptionLength = 2000
+
+// A new flat is a likely duplicate of an earlier one by the same owner in the same house
+// with the same rooms, a price within the band and matching area and floor when both are known.
+const (
+	DuplicatePriceBand     = 0.1
+	DuplicateAreaTolerance = 1.0
+)
+
 var (
 	ErrFlat_BadPrice   = errors.New("bad flat price")
 	ErrFlat_BadID      = errors.New("bad flat id")
@@ -23,48 +60,272 @@ var (
 	ErrFlat_BadNewFlat = errors.New("bad new flat for update")
 	ErrFlat_BadStatus  = errors.New("bad flat status")
 	ErrFlat_BadRequest = errors.New("bad request for create")
+	ErrFlat_BadFilter  = errors.New("bad flats filter")
+	ErrFlat_BadSort    = errors.New("bad flats sort")
+	ErrFlat_BadLimit   = errors.New("bad flats limit")
+	ErrFlat_BadCursor  = errors.New("bad flats cursor")
+
+	ErrFlat_BadArea        = errors.New("bad flat area")
+	ErrFlat_BadFloor       = errors.New("bad flat floor")
+	ErrFlat_BadDescription = errors.New("bad flat description")
+	ErrFlat_BadLayout      = errors.New("bad flat layout")
+	ErrFlat_BadBalcony     = errors.New("bad flat balcony")
+	ErrFlat_BadReason      = errors.New("bad decline reason")
+	ErrFlat_BadComment     = errors.New("bad moderator comment")
+
+	ErrFlat_NotFound     = errors.New("flat not found")
+	ErrFlat_NotOwner     = errors.New("flat belongs to another user")
+	ErrFlat_OnModeration = errors.New("flat is on moderation")
+	ErrFlat_Conflict     = errors.New("flat was changed concurrently")
+	ErrFlat_Archived     = errors.New("flat is archived")
+	ErrFlat_NotArchived  = errors.New("flat is not archived")
+	ErrFlat_NumberTaken  = errors.New("flat number is already taken in this house")
+
+	ErrFlat_VersionMismatch = errors.New("flat version does not match")
+	// ErrVersion_BadIfMatch is shared by flats and houses: the If-Match header is not a version
+	ErrVersion_BadIfMatch = errors.New("bad If-Match header")
+
+	ErrFlat_BadTransition = errors.New("bad flat status transition")
 )
 
+type FlatTransitionError struct {
+	From  string
+	To    string
+	Actor string
+}
+
+func (e *FlatTransitionError) Error() string {
+	return fmt.Sprintf("%s: %s can't move flat from %q to %q", ErrFlat_BadTransition, e.Actor, e.From, e.To)
+}
+
+func (e *FlatTransitionError) Is(target error) bool {
+	return target == ErrFlat_BadTransition
+}
+
 type Flat struct {
-	ID          int
-	HouseID     int
-	UserID      uuid.UUID
-	Price       int
-	Rooms       int
-	Status      string
-	ModeratorID int
+	ID             int
+	HouseID        int
+	UserID         uuid.UUID
+	Price          int
+	Rooms          int
+	Status         string
+	ModeratorID    uuid.UUID
+	CreateFlatDate time.Time
+	TotalArea      float64
+	LivingArea     float64
+	Floor          int
+	Floors         int
+	Description    string
+	Layout         string
+	Balcony        string
+	Version        int
+	DuplicateOf    int
+
+	ModerationExpiresAt time.Time
 }
 
 type CreateFlatRequest struct {
-	FlatID  int `json:"flat_id"`
-	HouseID int `json:"house_id"`
-	Price   int `json:"price"`
-	Rooms   int `json:"rooms"`
+	FlatID      int     `json:"flat_id,omitempty"`
+	HouseID     int     `json:"house_id"`
+	Price       int     `json:"price"`
+	Rooms       int     `json:"rooms"`
+	TotalArea   float64 `json:"total_area,omitempty"`
+	LivingArea  float64 `json:"living_area,omitempty"`
+	Floor       int     `json:"floor,omitempty"`
+	Floors      int     `json:"floors,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Layout      string  `json:"layout,omitempty"`
+	Balcony     string  `json:"balcony,omitempty"`
 }
 
 type UpdateFlatRequest struct {
-	ID      int    `json:"id"`
-	HouseID int    `json:"house_id"`
-	Status  string `json:"status,omitempty"`
+	ID         int    `json:"id"`
+	HouseID    int    `json:"house_id"`
+	Status     string `json:"status,omitempty"`
+	ReasonCode string `json:"reason_code,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	Version    int    `json:"-"`
+}
+
+type EditFlatRequest struct {
+	ID          int      `json:"id"`
+	HouseID     int      `json:"house_id"`
+	Price       *int     `json:"price,omitempty"`
+	Rooms       *int     `json:"rooms,omitempty"`
+	TotalArea   *float64 `json:"total_area,omitempty"`
+	LivingArea  *float64 `json:"living_area,omitempty"`
+	Floor       *int     `json:"floor,omitempty"`
+	Floors      *int     `json:"floors,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Layout      *string  `json:"layout,omitempty"`
+	Balcony     *string  `json:"balcony,omitempty"`
+	Version     int      `json:"-"`
+}
+
+type ArchiveFlatRequest struct {
+	ID      int `json:"id"`
+	HouseID int `json:"house_id"`
+	Version int `json:"-"`
+}
+
+type FlatFieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+type FlatEdit struct {
+	ID       int
+	FlatID   int
+	HouseID  int
+	UserID   uuid.UUID
+	Changes  map[string]FlatFieldChange
+	EditDate time.Time
+}
+
+type FlatEditResponse struct {
+	ID       int                        `json:"id"`
+	UserID   uuid.UUID                  `json:"user_id"`
+	Changes  map[string]FlatFieldChange `json:"changes"`
+	EditedAt string                     `json:"edited_at"`
+}
+
+type FlatEditsResponse struct {
+	Edits []FlatEditResponse `json:"edits"`
+}
+
+const (
+	OwnerActorKind     = "owner"
+	ModeratorActorKind = "moderator"
+	SystemActorKind    = "system"
+)
+
+type FlatStatusChange struct {
+	ID         int
+	FlatID     int
+	HouseID    int
+	FromStatus string
+	ToStatus   string
+	ActorID    uuid.UUID
+	Comment    string
+	ChangeDate time.Time
+}
+
+type FlatStatusChangeResponse struct {
+	FromStatus string `json:"from_status,omitempty"`
+	ToStatus   string `json:"to_status"`
+	Actor      string `json:"actor"`
+	ActorID    string `json:"actor_id,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	ChangedAt  string `json:"changed_at"`
+}
+
+type FlatHistoryResponse struct {
+	History []FlatStatusChangeResponse `json:"history"`
 }
 
 type CreateFlatResponse struct {
-	ID      int    `json:"id"`
-	HouseID int    `json:"house_id"`
-	Price   int    `json:"price"`
-	Rooms   int    `json:"rooms"`
-	Status  string `json:"status"`
+	ID            int     `json:"id"`
+	HouseID       int     `json:"house_id"`
+	Price         int     `json:"price"`
+	Rooms         int     `json:"rooms"`
+	Status        string  `json:"status"`
+	TotalArea     float64 `json:"total_area,omitempty"`
+	LivingArea    float64 `json:"living_area,omitempty"`
+	Floor         int     `json:"floor,omitempty"`
+	Floors        int     `json:"floors,omitempty"`
+	Description   string  `json:"description,omitempty"`
+	Layout        string  `json:"layout,omitempty"`
+	Balcony       string  `json:"balcony,omitempty"`
+	PricePerMeter float64 `json:"price_per_meter,omitempty"`
+	Version       int     `json:"version"`
+	DuplicateOf   int     `json:"duplicate_of,omitempty"`
+
+	ModerationExpiresAt string `json:"moderation_expires_at,omitempty"`
+}
+
+type FlatDetailResponse struct {
+	SingleFlatResponse
+	LastDecision *ModerationDecisionResponse `json:"last_decision,omitempty"`
+}
+
+type OwnerFlat struct {
+	Flat         Flat
+	LastDecision *ModerationDecision
+}
+
+type OwnerFlatsResponse struct {
+	Flats []FlatDetailResponse `json:"flats"`
+}
+
+type FlatSearchRequest struct {
+	PriceMin  int    `json:"price_min"`
+	PriceMax  int    `json:"price_max"`
+	Rooms     int    `json:"rooms"`
+	HouseID   int    `json:"house_id"`
+	Developer string `json:"developer"`
+	YearMin   int    `json:"year_min"`
+	YearMax   int    `json:"year_max"`
+	Sort      string `json:"sort"`
+	Limit     int    `json:"limit"`
+	Cursor    string `json:"cursor"`
+}
+
+type FlatSearchCursor struct {
+	Sort    string    `json:"sort"`
+	Price   int       `json:"price,omitempty"`
+	Date    time.Time `json:"date,omitempty"`
+	HouseID int       `json:"house_id"`
+	FlatID  int       `json:"flat_id"`
+}
+
+type FlatSearchFilter struct {
+	PriceMin  int
+	PriceMax  int
+	Rooms     int
+	HouseID   int
+	Developer string
+	YearMin   int
+	YearMax   int
+	Sort      string
+	Limit     int
+	After     *FlatSearchCursor
+}
+
+type FlatSearchResponse struct {
+	Flats      []SingleFlatResponse `json:"flats"`
+	NextCursor string               `json:"next_cursor,omitempty"`
 }
 
 type FlatUsecase interface {
 	Create(ctx context.Context, userID uuid.UUID, flatReq *CreateFlatRequest, lg *zap.Logger) (CreateFlatResponse, error)
 	Update(ctx context.Context, moderatorID uuid.UUID, newFlatData *UpdateFlatRequest, lg *zap.Logger) (CreateFlatResponse, error)
+	Search(ctx context.Context, req *FlatSearchRequest, lg *zap.Logger) (FlatSearchResponse, error)
+	Edit(ctx context.Context, userID uuid.UUID, req *EditFlatRequest, lg *zap.Logger) (CreateFlatResponse, error)
+	GetEdits(ctx context.Context, flatID int, houseID int, lg *zap.Logger) (FlatEditsResponse, error)
+	Archive(ctx context.Context, userID uuid.UUID, role string, req *ArchiveFlatRequest, lg *zap.Logger) (CreateFlatResponse, error)
+	Restore(ctx context.Context, userID uuid.UUID, role string, req *ArchiveFlatRequest, lg *zap.Logger) (CreateFlatResponse, error)
+	GetDetail(ctx context.Context, userID uuid.UUID, role string, flatID int, houseID int, lg *zap.Logger) (FlatDetailResponse, error)
+	GetHistory(ctx context.Context, userID uuid.UUID, role string, flatID int, houseID int, lg *zap.Logger) (FlatHistoryResponse, error)
+	GetOwnFlats(ctx context.Context, userID uuid.UUID, lg *zap.Logger) (OwnerFlatsResponse, error)
 }
 
 type FlatRepo interface {
-	Create(ctx context.Context, flat *Flat, lg *zap.Logger) (Flat, error)
+	Create(ctx context.Context, flat *Flat, verdict *AutoModerationVerdict, lg *zap.Logger) (Flat, error)
 	DeleteByID(ctx context.Context, id int, houseID int, lg *zap.Logger) error
-	Update(ctx context.Context, moderatorID uuid.UUID, newFlatData *Flat, lg *zap.Logger) (Flat, error)
+	Update(ctx context.Context, moderatorID uuid.UUID, oldFlat *Flat, newFlatData *Flat, decision *ModerationDecision,
+		lg *zap.Logger) (Flat, error)
 	GetByID(ctx context.Context, id int, houseID int, lg *zap.Logger) (Flat, error)
 	GetAll(ctx context.Context, offset int, limit int, lg *zap.Logger) ([]Flat, error)
+	Search(ctx context.Context, filter *FlatSearchFilter, lg *zap.Logger) ([]Flat, error)
+	Edit(ctx context.Context, oldFlat *Flat, newFlatData *Flat, edit *FlatEdit, lg *zap.Logger) (Flat, error)
+	GetEdits(ctx context.Context, flatID int, houseID int, lg *zap.Logger) ([]FlatEdit, error)
+	Archive(ctx context.Context, actorID uuid.UUID, flat *Flat, lg *zap.Logger) (Flat, error)
+	Restore(ctx context.Context, actorID uuid.UUID, flat *Flat, lg *zap.Logger) (Flat, error)
+	GetLastDecision(ctx context.Context, flatID int, houseID int, lg *zap.Logger) (*ModerationDecision, error)
+	GetStatusHistory(ctx context.Context, flatID int, houseID int, lg *zap.Logger) ([]FlatStatusChange, error)
+	GetByOwner(ctx context.Context, userID uuid.UUID, lg *zap.Logger) ([]OwnerFlat, error)
+	GetHousePriceStats(ctx context.Context, houseID int, lg *zap.Logger) (HousePriceStats, error)
+	FindDuplicate(ctx context.Context, flat *Flat, lg *zap.Logger) (int, error)
+	GetUnlinkedBatch(ctx context.Context, afterHouseID int, afterFlatID int, limit int, lg *zap.Logger) ([]Flat, error)
+	SetDuplicateOf(ctx context.Context, flat *Flat, duplicateOf int, lg *zap.Logger) error
 }
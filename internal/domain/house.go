@@ -11,65 +11,293 @@ import (
 const FlatThreshhold = 3
 
 var (
-	ErrHouse_BadRequest = errors.New("bad house request for create")
-	ErrHouse_BadID      = errors.New("bad house id")
-	ErrHouse_BadYear    = errors.New("bad house construct year")
+	ErrHouse_BadRequest      = errors.New("bad house request for create")
+	ErrHouse_BadID           = errors.New("bad house id")
+	ErrHouse_BadYear         = errors.New("bad house construct year")
+	ErrHouse_NotFound        = errors.New("house not found")
+	ErrHouse_BadFilter       = errors.New("bad house search filter")
+	ErrHouse_BadLimit        = errors.New("bad house search limit")
+	ErrHouse_BadCursor       = errors.New("bad house search cursor")
+	ErrHouse_BadEdit         = errors.New("bad house edit")
+	ErrHouse_HasFlats        = errors.New("house still has flats")
+	ErrHouse_Conflict        = errors.New("house was changed concurrently")
+	ErrHouse_VersionMismatch = errors.New("house version mismatch")
+	ErrHouse_BadLocation     = errors.New("bad house location")
+	ErrHouse_BadRadius       = errors.New("bad nearby search radius")
+	ErrHouse_BadQuery        = errors.New("bad house address query")
+)
+
+// MaxNearbyRadius limits GET /houses/nearby to a city-sized circle, in meters.
+const MaxNearbyRadius = 50000
+
+const (
+	MaxAddressQueryLength = 200
+	// AddressSimilarityThreshold is the lowest pg_trgm word similarity of a query to an address that still matches.
+	AddressSimilarityThreshold = 0.4
 )
 
 type House struct {
 	HouseID         int
 	Address         string
 	ConstructYear   int
+	DeveloperID     int
 	Developer       string
 	CreateHouseDate time.Time
 	UpdateFlatDate  time.Time
+	Version         int
+	Latitude        *float64
+	Longitude       *float64
+	// NormalizedAddress is only written: it backs the fuzzy address search
+	NormalizedAddress string
+}
+
+// GeoBox is a map viewport; MinLon > MaxLon means the box crosses the antimeridian.
+type GeoBox struct {
+	MinLat float64
+	MaxLat float64
+	MinLon float64
+	MaxLon float64
 }
 
 type CreateHouseRequest struct {
-	HomeID    int    `json:"id"`
-	Address   string `json:"address"`
-	Year      int    `json:"year"`
-	Developer string `json:"developer"`
+	HomeID      int      `json:"id"`
+	Address     string   `json:"address"`
+	Year        int      `json:"year"`
+	DeveloperID int      `json:"developer_id"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
 }
 
 type CreateHouseResponse struct {
-	HomeID    int    `json:"id"`
-	Address   string `json:"address"`
-	Year      int    `json:"year"`
-	Developer string `json:"developer"`
-	CreatedAt string `json:"created_at"`
-	UpdateAt  string `json:"update_at"`
+	HomeID      int      `json:"id"`
+	Address     string   `json:"address"`
+	Year        int      `json:"year"`
+	DeveloperID int      `json:"developer_id,omitempty"`
+	Developer   string   `json:"developer,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+	UpdateAt    string   `json:"update_at"`
+	Version     int      `json:"version"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+}
+
+type EditHouseRequest struct {
+	ID          int      `json:"-"`
+	Address     *string  `json:"address,omitempty"`
+	Year        *int     `json:"year,omitempty"`
+	DeveloperID *int     `json:"developer_id,omitempty"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+	Version     int      `json:"-"`
+}
+
+type DeleteHouseRequest struct {
+	ID      int
+	Version int
 }
 
 type FlatsByHouseRequest struct {
-	ID int `json:"id"`
+	ID       int    `json:"id"`
+	Rooms    int    `json:"rooms"`
+	PriceMin int    `json:"price_min"`
+	PriceMax int    `json:"price_max"`
+	Sort     string `json:"sort"`
+	Limit    int    `json:"limit"`
+	Cursor   string `json:"cursor"`
+}
+
+type FlatsByHouseCursor struct {
+	Sort   string `json:"sort"`
+	Value  int    `json:"value,omitempty"`
+	FlatID int    `json:"flat_id"`
+}
+
+type FlatsByHouseFilter struct {
+	Rooms    int
+	PriceMin int
+	PriceMax int
+	Sort     string
+	Limit    int
+	After    *FlatsByHouseCursor
 }
 
 type FlatsByHouseResponse struct {
-	Flats []SingleFlatResponse `json:"flats"`
+	Flats      []SingleFlatResponse `json:"flats"`
+	NextCursor string               `json:"next_cursor,omitempty"`
 }
 
 type SingleFlatResponse struct {
-	ID      int    `json:"id"`
-	HouseID int    `json:"house_id"`
-	Price   int    `json:"price"`
-	Rooms   int    `json:"rooms"`
-	Status  string `json:"status"`
+	ID            int     `json:"id"`
+	HouseID       int     `json:"house_id"`
+	Price         int     `json:"price"`
+	Rooms         int     `json:"rooms"`
+	Status        string  `json:"status"`
+	TotalArea     float64 `json:"total_area,omitempty"`
+	LivingArea    float64 `json:"living_area,omitempty"`
+	Floor         int     `json:"floor,omitempty"`
+	Floors        int     `json:"floors,omitempty"`
+	Description   string  `json:"description,omitempty"`
+	Layout        string  `json:"layout,omitempty"`
+	Balcony       string  `json:"balcony,omitempty"`
+	PricePerMeter float64 `json:"price_per_meter,omitempty"`
+	Version       int     `json:"version"`
+	DuplicateOf   int     `json:"duplicate_of,omitempty"`
+}
+
+type HouseSearchRequest struct {
+	DeveloperID      int
+	Developer        string
+	YearMin          int
+	YearMax          int
+	Address          string
+	HasApprovedFlats bool
+	Box              *GeoBox
+	Limit            int
+	Cursor           string
+}
+
+type HouseSearchCursor struct {
+	Date    time.Time `json:"date"`
+	HouseID int       `json:"house_id"`
+}
+
+type HouseSearchFilter struct {
+	DeveloperID      int
+	Developer        string
+	YearMin          int
+	YearMax          int
+	Address          string
+	HasApprovedFlats bool
+	Box              *GeoBox
+	Limit            int
+	After            *HouseSearchCursor
+}
+
+type HouseListItem struct {
+	House         House
+	Flats         int
+	ApprovedFlats int
+	Distance      float64
+}
+
+type HouseListItemResponse struct {
+	CreateHouseResponse
+	Flats         int `json:"flats"`
+	ApprovedFlats int `json:"approved_flats"`
+}
+
+type HouseSearchResponse struct {
+	Houses     []HouseListItemResponse `json:"houses"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+type HouseAddressSearchRequest struct {
+	Query string
+	Limit int
+}
+
+type HouseAddressFilter struct {
+	Query     string
+	Threshold float64
+	Limit     int
+}
+
+type HouseAddressMatch struct {
+	House      House
+	Similarity float64
+}
+
+type HouseAddressMatchResponse struct {
+	CreateHouseResponse
+	Highlight  string  `json:"highlight"`
+	Similarity float64 `json:"similarity"`
+}
+
+type HouseAddressSearchResponse struct {
+	Houses []HouseAddressMatchResponse `json:"houses"`
+}
+
+// HousePriceBucket is the number of approved flats of the house with the given rooms and price.
+type HousePriceBucket struct {
+	Rooms int
+	Price int
+	Flats int
+}
+
+type HouseRoomsStatsResponse struct {
+	Rooms       int     `json:"rooms"`
+	Flats       int     `json:"flats"`
+	PriceMin    int     `json:"price_min"`
+	PriceMedian float64 `json:"price_median"`
+	PriceMax    int     `json:"price_max"`
+	PriceAvg    float64 `json:"price_avg"`
+}
+
+type HouseStatsResponse struct {
+	HouseID         int                       `json:"house_id"`
+	ApprovedFlats   int                       `json:"approved_flats"`
+	PriceMin        int                       `json:"price_min"`
+	PriceMedian     float64                   `json:"price_median"`
+	PriceMax        int                       `json:"price_max"`
+	AvgPricePerRoom float64                   `json:"avg_price_per_room"`
+	Rooms           []HouseRoomsStatsResponse `json:"rooms"`
+}
+
+type HouseNearbyRequest struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64
+	Limit     int
+}
+
+type HouseNearbyFilter struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64
+	Limit     int
+}
+
+type HouseNearbyItemResponse struct {
+	HouseListItemResponse
+	Distance float64 `json:"distance"`
+}
+
+type HouseNearbyResponse struct {
+	Houses []HouseNearbyItemResponse `json:"houses"`
 }
 
 type HouseUsecase interface {
 	Create(ctx context.Context, req *CreateHouseRequest, lg *zap.Logger) (CreateHouseResponse, error)
-	GetFlatsByHouseID(ctx context.Context, id int, status string, lg *zap.Logger) (FlatsByHouseResponse, error)
+	Edit(ctx context.Context, req *EditHouseRequest, lg *zap.Logger) (CreateHouseResponse, error)
+	Delete(ctx context.Context, req *DeleteHouseRequest, lg *zap.Logger) error
+	GetFlatsByHouseID(ctx context.Context, req *FlatsByHouseRequest, status string, lg *zap.Logger) (FlatsByHouseResponse, error)
+	GetFlatsUpdateDate(ctx context.Context, id int, lg *zap.Logger) (time.Time, error)
+	Search(ctx context.Context, req *HouseSearchRequest, lg *zap.Logger) (HouseSearchResponse, error)
+	Nearby(ctx context.Context, req *HouseNearbyRequest, lg *zap.Logger) (HouseNearbyResponse, error)
+	SearchByAddress(ctx context.Context, req *HouseAddressSearchRequest, lg *zap.Logger) (HouseAddressSearchResponse, error)
+	GetStats(ctx context.Context, id int, lg *zap.Logger) (HouseStatsResponse, error)
 	SubscribeByID(ctx context.Context, id int, userID uuid.UUID, lg *zap.Logger) error
+	UnsubscribeByID(ctx context.Context, id int, userID uuid.UUID, lg *zap.Logger) error
+	CheckUnsubscribeToken(ctx context.Context, token string, lg *zap.Logger) (UnsubscribeResponse, error)
+	UnsubscribeByToken(ctx context.Context, token string, lg *zap.Logger) (UnsubscribeResponse, error)
+	GetSubscriptions(ctx context.Context, userID uuid.UUID, lg *zap.Logger) (SubscriptionsResponse, error)
 	Notifying(done chan bool, frequency time.Duration, timeout time.Duration, lg *zap.Logger)
 }
 
 type HouseRepo interface {
 	Create(ctx context.Context, house *House, lg *zap.Logger) (House, error)
-	DeleteByID(ctx context.Context, id int, lg *zap.Logger) error
-	Update(ctx context.Context, newHouseData *House, lg *zap.Logger) error
+	DeleteByID(ctx context.Context, house *House, lg *zap.Logger) error
+	Update(ctx context.Context, oldHouse *House, newHouseData *House, lg *zap.Logger) (House, error)
 	GetByID(ctx context.Context, id int, lg *zap.Logger) (House, error)
 	GetAll(ctx context.Context, offset int, limit int, lg *zap.Logger) ([]House, error)
-	GetFlatsByHouseID(ctx context.Context, id int, status string, lg *zap.Logger) ([]Flat, error)
+	GetFlatsByHouseID(ctx context.Context, id int, status string, filter *FlatsByHouseFilter, lg *zap.Logger) ([]Flat, error)
+	Search(ctx context.Context, filter *HouseSearchFilter, lg *zap.Logger) ([]HouseListItem, error)
+	Nearby(ctx context.Context, filter *HouseNearbyFilter, lg *zap.Logger) ([]HouseListItem, error)
+	SearchByAddress(ctx context.Context, filter *HouseAddressFilter, lg *zap.Logger) ([]HouseAddressMatch, error)
+	GetUnnormalizedBatch(ctx context.Context, afterHouseID int, limit int, lg *zap.Logger) ([]House, error)
+	SetNormalizedAddress(ctx context.Context, house *House, lg *zap.Logger) (int64, error)
+	GetPriceBuckets(ctx context.Context, id int, lg *zap.Logger) ([]HousePriceBucket, error)
 	SubscribeByID(ctx context.Context, id int, userID uuid.UUID, lg *zap.Logger) error
+	UnsubscribeByID(ctx context.Context, id int, userID uuid.UUID, lg *zap.Logger) error
+	GetSubscriptions(ctx context.Context, userID uuid.UUID, lg *zap.Logger) ([]Subscription, error)
 }
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrModeration_EmptyQueue = errors.New("moderation queue is empty")
+	ErrModeration_BadLimit   = errors.New("bad moderation queue limit")
+	ErrModeration_NotClaimed = errors.New("flat is not claimed by this moderator")
+)
+
+const MaxCommentLength = 1000
+
+type DeclineReason struct {
+	Code  string
+	Title string
+}
+
+type ModerationDecision struct {
+	ID           int
+	FlatID       int
+	HouseID      int
+	ModeratorID  uuid.UUID
+	Decision     string
+	ReasonCode   string
+	Comment      string
+	DecisionDate time.Time
+}
+
+type ModerationDecisionResponse struct {
+	Decision   string `json:"decision"`
+	ReasonCode string `json:"reason_code,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	DecidedAt  string `json:"decided_at"`
+}
+
+type RenewClaimRequest struct {
+	ID      int `json:"id"`
+	HouseID int `json:"house_id"`
+}
+
+type ModerationQueueItem struct {
+	FlatID      int
+	HouseID     int
+	WaitingSec  int64
+	DuplicateOf int
+}
+
+type ModerationQueue struct {
+	Depth         int
+	Claimed       int
+	OldestAgeSec  int64
+	AverageAgeSec int64
+	Items         []ModerationQueueItem
+}
+
+type ModerationQueueItemResponse struct {
+	FlatID      int   `json:"flat_id"`
+	HouseID     int   `json:"house_id"`
+	WaitingSec  int64 `json:"waiting_sec"`
+	DuplicateOf int   `json:"duplicate_of,omitempty"`
+}
+
+type ModerationQueueResponse struct {
+	Depth         int                           `json:"depth"`
+	Claimed       int                           `json:"claimed"`
+	OldestAgeSec  int64                         `json:"oldest_age_sec"`
+	AverageAgeSec int64                         `json:"average_age_sec"`
+	Flats         []ModerationQueueItemResponse `json:"flats"`
+}
+
+type ModerationUsecase interface {
+	Claim(ctx context.Context, moderatorID uuid.UUID, lg *zap.Logger) (CreateFlatResponse, error)
+	GetQueue(ctx context.Context, limit int, lg *zap.Logger) (ModerationQueueResponse, error)
+	Renew(ctx context.Context, moderatorID uuid.UUID, req *RenewClaimRequest, lg *zap.Logger) (CreateFlatResponse, error)
+}
+
+type ModerationRepo interface {
+	Claim(ctx context.Context, moderatorID uuid.UUID, expiresAt time.Time, lg *zap.Logger) (Flat, error)
+	GetQueue(ctx context.Context, limit int, lg *zap.Logger) (ModerationQueue, error)
+	Renew(ctx context.Context, flatID int, houseID int, moderatorID uuid.UUID, expiresAt time.Time, lg *zap.Logger) (Flat, error)
+	ReleaseExpired(ctx context.Context, now time.Time, lg *zap.Logger) (int64, error)
+}
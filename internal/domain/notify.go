@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -14,12 +15,14 @@ type Notify struct {
 	ID       int
 	FlatID   int
 	HouseID  int
+	UserID   uuid.UUID
 	UserMail string
 	Status   string
 }
 
 type NotifySender interface {
-	SendEmail(ctx context.Context, recipient string, message string) error
+	// SendEmail delivers the message; unsubscribeURL, when not empty, is the one-click unsubscribe link
+	SendEmail(ctx context.Context, recipient string, message string, unsubscribeURL string) error
 }
 
 type NotifyRepo interface {
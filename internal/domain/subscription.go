@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSubscription_NotFound = errors.New("subscription not found")
+	ErrSubscription_BadToken = errors.New("bad unsubscribe token")
+)
+
+type Subscription struct {
+	HouseID       int
+	Address       string
+	SubscribeDate time.Time
+}
+
+type SubscriptionResponse struct {
+	HouseID      int    `json:"house_id"`
+	Address      string `json:"address"`
+	SubscribedAt string `json:"subscribed_at"`
+}
+
+type SubscriptionsResponse struct {
+	Subscriptions []SubscriptionResponse `json:"subscriptions"`
+}
+
+type UnsubscribeResponse struct {
+	HouseID    int  `json:"house_id"`
+	Subscribed bool `json:"subscribed"`
+}
@@ -14,7 +14,7 @@ func NewSender() *Sender {
 	return &Sender{}
 }
 
-func (s *Sender) SendEmail(ctx context.Context, recipient string, message string) error {
+func (s *Sender) SendEmail(ctx context.Context, recipient string, message string, unsubscribeURL string) error {
 	duration := time.Duration(rand.Int63n(3000)) * time.Millisecond
 	time.Sleep(duration)
 
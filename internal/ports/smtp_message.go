@@ -0,0 +1,102 @@
+package ports
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// buildMessage renders the notification as multipart/alternative with a plain-text and an HTML part.
+// A not empty unsubscribeURL is announced in List-Unsubscribe headers, so mail clients can offer
+// the RFC 8058 one-click unsubscribe, which POSTs to the link.
+func buildMessage(from *mail.Address, to *mail.Address, subject string, text string, unsubscribeURL string,
+	date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", textToHTML(text)},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		_, err = encoder.Write([]byte(part.content))
+		if err != nil {
+			return nil, err
+		}
+		err = encoder.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", "<" + uuid.NewString() + "@" + addressDomain(from.Address) + ">"},
+	}
+	if unsubscribeURL != "" {
+		headers = append(headers,
+			[2]string{"List-Unsubscribe", "<" + unsubscribeURL + ">"},
+			[2]string{"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"})
+	}
+	headers = append(headers,
+		[2]string{"MIME-Version", "1.0"},
+		[2]string{"Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": writer.Boundary()})})
+
+	var msg bytes.Buffer
+	for _, header := range headers {
+		msg.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// textToHTML escapes the text, keeps its lines and turns http(s) addresses into links.
+func textToHTML(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		words := strings.Split(line, " ")
+		for j, word := range words {
+			escaped := html.EscapeString(word)
+			if strings.HasPrefix(word, "http://") || strings.HasPrefix(word, "https://") {
+				escaped = `<a href="` + escaped + `">` + escaped + `</a>`
+			}
+			words[j] = escaped
+		}
+		lines[i] = strings.Join(words, " ")
+	}
+
+	return "<html><body><p>" + strings.Join(lines, "<br>\n") + "</p></body></html>"
+}
+
+func addressDomain(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
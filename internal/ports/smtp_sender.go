@@ -0,0 +1,205 @@
+package ports
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SMTPStartTLS    = "starttls"
+	SMTPImplicitTLS = "tls"
+	SMTPNoTLS       = "none"
+)
+
+type SMTPSettings struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Subject  string
+	// TLSMode is SMTPStartTLS (required upgrade), SMTPImplicitTLS (port 465 style) or SMTPNoTLS
+	TLSMode            string
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+}
+
+// SMTPSender delivers notifications over SMTP. One connection is kept open and reused
+// between messages; when a reused connection turns out to be dropped before the server took the message,
+// the message is sent once more on a new one.
+type SMTPSender struct {
+	settings SMTPSettings
+	from     *mail.Address
+
+	mu     sync.Mutex
+	conn   net.Conn
+	client *smtp.Client
+}
+
+func NewSMTPSender(settings SMTPSettings) (*SMTPSender, error) {
+	if settings.Host == "" || settings.Port < 1 {
+		return nil, errors.New("smtp sender: host and port are required")
+	}
+	if settings.TLSMode != SMTPStartTLS && settings.TLSMode != SMTPImplicitTLS && settings.TLSMode != SMTPNoTLS {
+		return nil, fmt.Errorf("smtp sender: unknown tls mode %q", settings.TLSMode)
+	}
+	from, err := mail.ParseAddress(settings.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp sender: bad from address %q: %v", settings.From, err.Error())
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 10 * time.Second
+	}
+
+	return &SMTPSender{settings: settings, from: from}, nil
+}
+
+func (s *SMTPSender) SendEmail(ctx context.Context, recipient string, message string, unsubscribeURL string) error {
+	to, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return fmt.Errorf("smtp sender: bad recipient %q: %v", recipient, err.Error())
+	}
+	msg, err := buildMessage(s.from, to, s.settings.Subject, message, unsubscribeURL, time.Now())
+	if err != nil {
+		return fmt.Errorf("smtp sender: build message error: %v", err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	retry, err := s.send(ctx, to.Address, msg)
+	if err != nil {
+		s.reset()
+	}
+	if err != nil && retry && ctx.Err() == nil {
+		// the server may have closed the idle connection
+		_, err = s.send(ctx, to.Address, msg)
+		if err != nil {
+			s.reset()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("smtp sender: send error: %v", err.Error())
+	}
+
+	return nil
+}
+
+// Close ends the kept connection.
+func (s *SMTPSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Quit()
+	s.client, s.conn = nil, nil
+	return err
+}
+
+// send reports whether a failed message may be sent again: only when the connection was reused
+// and the server had not accepted DATA yet. Once the payload went out, the server may have queued it,
+// and a resend could deliver the message twice.
+func (s *SMTPSender) send(ctx context.Context, recipient string, msg []byte) (bool, error) {
+	reused := s.client != nil
+	if !reused {
+		err := s.dial(ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+	s.setDeadline(ctx)
+
+	err := s.client.Mail(s.from.Address)
+	if err != nil {
+		return reused, err
+	}
+	err = s.client.Rcpt(recipient)
+	if err != nil {
+		return reused, err
+	}
+	writer, err := s.client.Data()
+	if err != nil {
+		return reused, err
+	}
+	_, err = writer.Write(msg)
+	if err != nil {
+		return false, err
+	}
+
+	return false, writer.Close()
+}
+
+func (s *SMTPSender) dial(ctx context.Context) error {
+	tlsConfig := &tls.Config{
+		ServerName:         s.settings.Host,
+		InsecureSkipVerify: s.settings.InsecureSkipVerify,
+	}
+
+	dialer := net.Dialer{Timeout: s.settings.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.settings.Host, strconv.Itoa(s.settings.Port)))
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.setDeadline(ctx)
+
+	if s.settings.TLSMode == SMTPImplicitTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	client, err := smtp.NewClient(conn, s.settings.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if s.settings.TLSMode == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return errors.New("server does not support STARTTLS")
+		}
+		err = client.StartTLS(tlsConfig)
+		if err != nil {
+			client.Close()
+			return err
+		}
+	}
+
+	if s.settings.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.settings.Username, s.settings.Password, s.settings.Host))
+		if err != nil {
+			client.Close()
+			return err
+		}
+	}
+
+	s.client = client
+	return nil
+}
+
+// setDeadline bounds the next exchange by the context deadline, or by the timeout without one.
+func (s *SMTPSender) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.settings.Timeout)
+	}
+	s.conn.SetDeadline(deadline)
+}
+
+func (s *SMTPSender) reset() {
+	if s.client != nil {
+		s.client.Close()
+	} else if s.conn != nil {
+		s.conn.Close()
+	}
+	s.client, s.conn = nil, nil
+}
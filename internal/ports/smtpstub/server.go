@@ -0,0 +1,307 @@
+// Package smtpstub is an in-process SMTP server for tests: it accepts mail on a local port,
+// optionally behind STARTTLS and AUTH PLAIN, and keeps every message instead of delivering it.
+package smtpstub
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	// Username and Password, when set, make AUTH PLAIN required before MAIL
+	Username string
+	Password string
+	// StartTLS offers STARTTLS with a self-signed certificate for localhost
+	StartTLS bool
+}
+
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+	// TLS and User describe the session the message came in
+	TLS  bool
+	User string
+}
+
+type Server struct {
+	Addr string
+
+	options   Options
+	tlsConfig *tls.Config
+	listener  net.Listener
+	wg        sync.WaitGroup
+
+	mu          sync.Mutex
+	messages    []Message
+	connections int
+	open        map[net.Conn]struct{}
+	hangUp      bool
+}
+
+// Start listens on a free port of 127.0.0.1; Addr holds the address to dial.
+func Start(options Options) (*Server, error) {
+	server := &Server{options: options, open: make(map[net.Conn]struct{})}
+	if options.StartTLS {
+		certificate, err := selfSignedCertificate()
+		if err != nil {
+			return nil, err
+		}
+		server.tlsConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	server.listener = listener
+	server.Addr = listener.Addr().String()
+
+	server.wg.Add(1)
+	go server.accept()
+
+	return server, nil
+}
+
+// Messages returns the messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Connections returns the number of connections accepted so far.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// DropConnections closes the open connections, as a server does with idle clients.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.open {
+		conn.Close()
+	}
+}
+
+// HangUpAfterNextData makes the server keep the next message and close the connection
+// without answering, as when the reply is lost after the message was queued.
+func (s *Server) HangUpAfterNextData() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hangUp = true
+}
+
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.DropConnections()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.connections++
+		s.open[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.open, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			newSession(s, conn).serve()
+		}()
+	}
+}
+
+type session struct {
+	server *Server
+	conn   net.Conn
+	text   *textproto.Conn
+
+	tls  bool
+	user string
+	from string
+	to   []string
+}
+
+func newSession(server *Server, conn net.Conn) *session {
+	return &session{server: server, conn: conn, text: textproto.NewConn(conn)}
+}
+
+func (c *session) reply(code int, lines ...string) {
+	for i, line := range lines {
+		separator := " "
+		if i < len(lines)-1 {
+			separator = "-"
+		}
+		c.text.PrintfLine("%d%s%s", code, separator, line)
+	}
+}
+
+func (c *session) serve() {
+	c.reply(220, "smtpstub ready")
+	for {
+		line, err := c.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			extensions := []string{"smtpstub", "8BITMIME"}
+			if c.server.tlsConfig != nil && !c.tls {
+				extensions = append(extensions, "STARTTLS")
+			}
+			if c.server.options.Username != "" {
+				extensions = append(extensions, "AUTH PLAIN")
+			}
+			c.reply(250, extensions...)
+		case "HELO":
+			c.reply(250, "smtpstub")
+		case "STARTTLS":
+			if c.server.tlsConfig == nil || c.tls {
+				c.reply(502, "STARTTLS not available")
+				continue
+			}
+			c.reply(220, "ready to start TLS")
+			tlsConn := tls.Server(c.conn, c.server.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			c.conn, c.text = tlsConn, textproto.NewConn(tlsConn)
+			c.tls, c.user, c.from, c.to = true, "", "", nil
+		case "AUTH":
+			c.auth(arg)
+		case "MAIL":
+			if c.server.options.Username != "" && c.user == "" {
+				c.reply(530, "authentication required")
+				continue
+			}
+			c.from, c.to = address(arg), nil
+			c.reply(250, "ok")
+		case "RCPT":
+			if c.from == "" {
+				c.reply(503, "need MAIL first")
+				continue
+			}
+			c.to = append(c.to, address(arg))
+			c.reply(250, "ok")
+		case "DATA":
+			if len(c.to) == 0 {
+				c.reply(503, "need RCPT first")
+				continue
+			}
+			c.reply(354, "end data with <CR><LF>.<CR><LF>")
+			data, err := c.text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			c.server.mu.Lock()
+			c.server.messages = append(c.server.messages, Message{
+				From: c.from, To: c.to, Data: data, TLS: c.tls, User: c.user,
+			})
+			hangUp := c.server.hangUp
+			c.server.hangUp = false
+			c.server.mu.Unlock()
+			if hangUp {
+				return
+			}
+			c.from, c.to = "", nil
+			c.reply(250, "queued")
+		case "RSET":
+			c.from, c.to = "", nil
+			c.reply(250, "ok")
+		case "NOOP":
+			c.reply(250, "ok")
+		case "QUIT":
+			c.reply(221, "bye")
+			return
+		default:
+			c.reply(502, "command not implemented")
+		}
+	}
+}
+
+func (c *session) auth(arg string) {
+	mechanism, response, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") || c.server.options.Username == "" {
+		c.reply(504, "mechanism not supported")
+		return
+	}
+	if response == "" {
+		c.reply(334, "")
+		line, err := c.text.ReadLine()
+		if err != nil {
+			return
+		}
+		response = line
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	parts := strings.Split(string(decoded), "\x00")
+	if err != nil || len(parts) != 3 ||
+		parts[1] != c.server.options.Username || parts[2] != c.server.options.Password {
+		c.reply(535, "authentication failed")
+		return
+	}
+	c.user = parts[1]
+	c.reply(235, "authenticated")
+}
+
+// address takes the mailbox out of "FROM:<a@b>" and "TO:<a@b>".
+func address(arg string) string {
+	_, value, _ := strings.Cut(arg, ":")
+	value, _, _ = strings.Cut(strings.TrimSpace(value), " ")
+	return strings.Trim(value, "<>")
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtpstub"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"avito-test-task/internal/domain"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type PostgresDeveloperRepo struct {
+	db           *pgxpool.Pool
+	retryAdapter IPostgresRetryAdapter
+}
+
+func NewPostgresDeveloperRepo(db *pgxpool.Pool, retryAdapter IPostgresRetryAdapter) *PostgresDeveloperRepo {
+	return &PostgresDeveloperRepo{
+		db:           db,
+		retryAdapter: retryAdapter,
+	}
+}
+
+const developerColumns = `developer_id, name, create_developer_date`
+
+// developerByNameCondition matches houses whose developer is spelled like the argument.
+const developerByNameCondition = `developer_id in (select developer_id from developers
+	where normalized_name = normalize_developer_name($%d))`
+
+func scanDeveloper(row pgx.Row, developer *domain.Developer) error {
+	return row.Scan(&developer.DeveloperID, &developer.Name, &developer.CreateDeveloperDate)
+}
+
+// developerWriteError maps constraint violations of developer writes to domain errors.
+func developerWriteError(err error) error {
+	switch {
+	case isPgError(err, uniqueViolationCode):
+		return domain.ErrDeveloper_Exists
+	case isPgError(err, checkViolationCode):
+		return domain.ErrDeveloper_BadName
+	case isForeignKeyViolation(err):
+		return domain.ErrDeveloper_HasHouses
+	}
+	return nil
+}
+
+func (p *PostgresDeveloperRepo) Create(ctx context.Context, developer *domain.Developer, lg *zap.Logger) (domain.Developer, error) {
+	lg.Info("postgres developer repo: create", zap.String("name", developer.Name))
+
+	var createdDeveloper domain.Developer
+	query := `insert into developers(name) values ($1) returning ` + developerColumns
+	rows, err := p.retryAdapter.Query(ctx, query, developer.Name)
+	if err != nil {
+		lg.Warn("postgres developer repo: create error", zap.Error(err))
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: create error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		err = rows.Err()
+		if domainErr := developerWriteError(err); domainErr != nil {
+			lg.Warn("postgres developer repo: create error", zap.Error(err))
+			return domain.Developer{}, fmt.Errorf("postgres developer repo: create error: %w", domainErr)
+		}
+		lg.Warn("postgres developer repo: create error", zap.Error(err))
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: create error: %v", err)
+	}
+	err = scanDeveloper(rows, &createdDeveloper)
+	if err != nil {
+		lg.Warn("postgres developer repo: create error", zap.Error(err))
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: create error: %v", err.Error())
+	}
+
+	return createdDeveloper, nil
+}
+
+func (p *PostgresDeveloperRepo) GetByID(ctx context.Context, id int, lg *zap.Logger) (domain.Developer, error) {
+	lg.Info("postgres developer repo: get by id", zap.Int("developer_id", id))
+
+	var developer domain.Developer
+	query := `select ` + developerColumns + ` from developers where developer_id=$1`
+	rows, err := p.retryAdapter.Query(ctx, query, id)
+	if err != nil {
+		lg.Warn("postgres developer repo: get by id error", zap.Error(err))
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: get by id error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			lg.Warn("postgres developer repo: get by id error", zap.Error(err))
+			return domain.Developer{}, fmt.Errorf("postgres developer repo: get by id error: %v", err.Error())
+		}
+		lg.Warn("postgres developer repo: get by id error: developer not found")
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: get by id error: %w", domain.ErrDeveloper_NotFound)
+	}
+	err = scanDeveloper(rows, &developer)
+	if err != nil {
+		lg.Warn("postgres developer repo: get by id error", zap.Error(err))
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: get by id error: %v", err.Error())
+	}
+
+	return developer, nil
+}
+
+func (p *PostgresDeveloperRepo) GetAll(ctx context.Context, filter *domain.DevelopersFilter, lg *zap.Logger) ([]domain.Developer, error) {
+	lg.Info("postgres developer repo: get all", zap.Int("limit", filter.Limit))
+
+	builder := conditionBuilder{}
+	if filter.After != nil {
+		builder.add("(name, developer_id) > ($%d, $%d)", filter.After.Name, filter.After.DeveloperID)
+	}
+
+	query := fmt.Sprintf(`select %s
+		from developers
+		where %s
+		order by name, developer_id
+		limit $%d`, developerColumns, builder.where(), builder.arg(filter.Limit))
+	rows, err := p.retryAdapter.Query(ctx, query, builder.args...)
+	if err != nil {
+		lg.Warn("postgres developer repo: get all error", zap.Error(err))
+		return nil, fmt.Errorf("postgres developer repo: get all error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var developers []domain.Developer
+	for rows.Next() {
+		var developer domain.Developer
+		err = scanDeveloper(rows, &developer)
+		if err != nil {
+			lg.Warn("postgres developer repo: get all error: scan developer error", zap.Error(err))
+			continue
+		}
+		developers = append(developers, developer)
+	}
+
+	return developers, rows.Err()
+}
+
+func (p *PostgresDeveloperRepo) Update(ctx context.Context, developer *domain.Developer, lg *zap.Logger) (domain.Developer, error) {
+	lg.Info("postgres developer repo: update", zap.Int("developer_id", developer.DeveloperID))
+
+	var updatedDeveloper domain.Developer
+	query := `update developers set name=$1 where developer_id=$2 returning ` + developerColumns
+	rows, err := p.retryAdapter.Query(ctx, query, developer.Name, developer.DeveloperID)
+	if err != nil {
+		lg.Warn("postgres developer repo: update error", zap.Error(err))
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: update error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		err = rows.Err()
+		if domainErr := developerWriteError(err); domainErr != nil {
+			lg.Warn("postgres developer repo: update error", zap.Error(err))
+			return domain.Developer{}, fmt.Errorf("postgres developer repo: update error: %w", domainErr)
+		}
+		if err != nil {
+			lg.Warn("postgres developer repo: update error", zap.Error(err))
+			return domain.Developer{}, fmt.Errorf("postgres developer repo: update error: %v", err.Error())
+		}
+		lg.Warn("postgres developer repo: update error: developer not found")
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: update error: %w", domain.ErrDeveloper_NotFound)
+	}
+	err = scanDeveloper(rows, &updatedDeveloper)
+	if err != nil {
+		lg.Warn("postgres developer repo: update error", zap.Error(err))
+		return domain.Developer{}, fmt.Errorf("postgres developer repo: update error: %v", err.Error())
+	}
+
+	return updatedDeveloper, nil
+}
+
+// DeleteByID removes a developer; the houses foreign key keeps developers that still have houses.
+func (p *PostgresDeveloperRepo) DeleteByID(ctx context.Context, id int, lg *zap.Logger) error {
+	lg.Info("postgres developer repo: delete by id", zap.Int("developer_id", id))
+
+	query := `delete from developers where developer_id=$1 returning developer_id`
+	rows, err := p.retryAdapter.Query(ctx, query, id)
+	if err != nil {
+		lg.Warn("postgres developer repo: delete by id error", zap.Error(err))
+		return fmt.Errorf("postgres developer repo: delete by id error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		err = rows.Err()
+		if domainErr := developerWriteError(err); domainErr != nil {
+			lg.Warn("postgres developer repo: delete by id error", zap.Error(err))
+			return fmt.Errorf("postgres developer repo: delete by id error: %w", domainErr)
+		}
+		if err != nil {
+			lg.Warn("postgres developer repo: delete by id error", zap.Error(err))
+			return fmt.Errorf("postgres developer repo: delete by id error: %v", err.Error())
+		}
+		lg.Warn("postgres developer repo: delete by id error: developer not found")
+		return fmt.Errorf("postgres developer repo: delete by id error: %w", domain.ErrDeveloper_NotFound)
+	}
+
+	return nil
+}
@@ -3,9 +3,11 @@ package repo
 import (
 	"avito-test-task/internal/domain"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 	"time"
@@ -23,7 +25,154 @@ func NewPostgresFlatRepo(db *pgxpool.Pool, retryAdapter IPostgresRetryAdapter) *
 	}
 }
 
-func (p *PostgresFlatRepo) Create(ctx context.Context, flat *domain.Flat, lg *zap.Logger) (domain.Flat, error) {
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+	checkViolationCode      = "23514"
+)
+
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+func isForeignKeyViolation(err error) bool {
+	return isPgError(err, foreignKeyViolationCode)
+}
+
+const flatColumns = `flat_id, house_id, user_id, price, rooms, status, moderator_id,
+	total_area, living_area, floor, floors, description, layout, balcony, moderation_expires_at, version, duplicate_of`
+
+func scanFlat(row pgx.Row, flat *domain.Flat, extra ...any) error {
+	var (
+		moderatorID *uuid.UUID
+		expiresAt   *time.Time
+		duplicateOf *int
+	)
+	dest := []any{&flat.ID, &flat.HouseID, &flat.UserID, &flat.Price, &flat.Rooms, &flat.Status,
+		&moderatorID, &flat.TotalArea, &flat.LivingArea, &flat.Floor, &flat.Floors,
+		&flat.Description, &flat.Layout, &flat.Balcony, &expiresAt, &flat.Version, &duplicateOf}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return err
+	}
+
+	flat.ModeratorID = uuid.Nil
+	if moderatorID != nil {
+		flat.ModeratorID = *moderatorID
+	}
+	flat.ModerationExpiresAt = time.Time{}
+	if expiresAt != nil {
+		flat.ModerationExpiresAt = *expiresAt
+	}
+	flat.DuplicateOf = 0
+	if duplicateOf != nil {
+		flat.DuplicateOf = *duplicateOf
+	}
+	return nil
+}
+
+// nullableUUID stores uuid.Nil as null.
+func nullableUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+// insertStatusChange appends a row to the flat status history inside the caller's transaction.
+// An empty fromStatus means the flat has just been created.
+func insertStatusChange(ctx context.Context, tx pgx.Tx, flat *domain.Flat, fromStatus string,
+	actorID uuid.UUID, comment string) error {
+	query := `insert into flat_status_history(flat_id, house_id, from_status, to_status, actor_id, comment)
+		values ($1, $2, nullif($3::text, '')::flat_status, $4, $5, $6)`
+	_, err := tx.Exec(ctx, query, flat.ID, flat.HouseID, fromStatus, flat.Status, nullableUUID(actorID), comment)
+	return err
+}
+
+// insertDecision stores a moderation decision; automatic decisions have no moderator.
+func insertDecision(ctx context.Context, tx pgx.Tx, decision *domain.ModerationDecision) error {
+	query := `insert into moderation_decisions(flat_id, house_id, moderator_id, decision,
+			reason_code, comment, decision_date)
+		values ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := tx.Exec(ctx, query, decision.FlatID, decision.HouseID, nullableUUID(decision.ModeratorID),
+		decision.Decision, decision.ReasonCode, decision.Comment, time.Now())
+	return err
+}
+
+// insertCreationHistory records the new flat. When an auto-moderation rule decided its status,
+// the decision is written as a second history step without an actor and into moderation_decisions.
+func insertCreationHistory(ctx context.Context, tx pgx.Tx, flat *domain.Flat, verdict *domain.AutoModerationVerdict) error {
+	created := *flat
+	created.Status = domain.CreatedStatus
+
+	// a flagged flat stays in the queue, the rule is noted on its creation step
+	var comment string
+	if verdict != nil && flat.Status == domain.CreatedStatus {
+		comment = verdict.Comment
+	}
+	err := insertStatusChange(ctx, tx, &created, "", flat.UserID, comment)
+	if err != nil || verdict == nil || flat.Status == domain.CreatedStatus {
+		return err
+	}
+
+	err = insertStatusChange(ctx, tx, flat, domain.CreatedStatus, uuid.Nil, verdict.Comment)
+	if err != nil {
+		return err
+	}
+
+	return insertDecision(ctx, tx, &domain.ModerationDecision{
+		FlatID:     flat.ID,
+		HouseID:    flat.HouseID,
+		Decision:   flat.Status,
+		ReasonCode: verdict.ReasonCode,
+		Comment:    verdict.Comment,
+	})
+}
+
+// insertApprovalNotifies queues a notification for every subscriber of the house once the flat is approved,
+// inside the caller's transaction. The unique index keeps one notification per subscriber and flat,
+// so approving the flat again announces nothing.
+func insertApprovalNotifies(ctx context.Context, tx pgx.Tx, flat *domain.Flat) error {
+	if flat.Status != domain.ApprovedStatus {
+		return nil
+	}
+
+	query := `insert into new_flats_outbox(flat_id, house_id, user_id, mail, status)
+		select $1, s.house_id, u.user_id, u.mail, $3
+		from subscribers s
+		join users u on u.user_id = s.user_id
+		where s.house_id = $2
+		on conflict (flat_id, house_id, user_id) do nothing`
+	_, err := tx.Exec(ctx, query, flat.ID, flat.HouseID, domain.NoSendedNotifyStatus)
+	return err
+}
+
+// touchHouse moves update_flat_date of the house, the validator of its flat list, inside the caller's transaction.
+// clock_timestamp() is taken after the row lock, so the dates of one house grow in commit order.
+func touchHouse(ctx context.Context, tx pgx.Tx, houseID int) error {
+	_, err := tx.Exec(ctx, `update houses set update_flat_date=clock_timestamp() where house_id=$1`, houseID)
+	return err
+}
+
+// nullableInt stores 0 as null.
+func nullableInt(value int) *int {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
+
+// nullableTime stores the zero time as null.
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (p *PostgresFlatRepo) Create(ctx context.Context, flat *domain.Flat, verdict *domain.AutoModerationVerdict,
+	lg *zap.Logger) (domain.Flat, error) {
 	lg.Info("postgres flat repo: create")
 
 	var (
@@ -45,21 +194,53 @@ func (p *PostgresFlatRepo) Create(ctx context.Context, flat *domain.Flat, lg *za
 		}
 	}()
 
-	query := `insert into flats(flat_id, house_id, user_id, price, rooms, status)
-			values ($1, $2, $3, $4, $5, $6) 
-			returning flat_id, house_id, user_id, price, rooms, status`
-	err = tx.QueryRow(ctx, query, flat.ID, flat.HouseID, flat.UserID,
-		flat.Price, flat.Rooms, domain.CreatedStatus).Scan(&createdFlat.ID,
-		&createdFlat.HouseID, &createdFlat.UserID, &createdFlat.Price, &createdFlat.Rooms,
-		&createdFlat.Status)
+	// the house row stays locked until commit, so concurrent creates in one house
+	// get consecutive numbers; a client-supplied number only moves the counter forward.
+	// The house version covers its own attributes, so a new flat leaves it as is
+	query := `update houses set update_flat_date=clock_timestamp(),
+			last_flat_id=(case when $2 = 0 then last_flat_id + 1 else greatest(last_flat_id, $2) end)
+		where house_id=$1
+		returning last_flat_id`
+	var lastFlatID int
+	err = tx.QueryRow(ctx, query, flat.HouseID, flat.ID).Scan(&lastFlatID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		lg.Warn("postgres flat repo: create error: house not found", zap.Int("house_id", flat.HouseID))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: create error: %w", domain.ErrHouse_NotFound)
+	}
+	if err != nil {
+		lg.Warn("postgres flat repo: create error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: create error: %v", err.Error())
+	}
+
+	flatID := flat.ID
+	if flatID == 0 {
+		flatID = lastFlatID
+	}
+
+	query = `insert into flats(flat_id, house_id, user_id, price, rooms, status,
+				total_area, living_area, floor, floors, description, layout, balcony, duplicate_of)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			returning ` + flatColumns
+	err = scanFlat(tx.QueryRow(ctx, query, flatID, flat.HouseID, flat.UserID,
+		flat.Price, flat.Rooms, flat.Status, flat.TotalArea, flat.LivingArea,
+		flat.Floor, flat.Floors, flat.Description, flat.Layout, flat.Balcony, nullableInt(flat.DuplicateOf)), &createdFlat)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		lg.Warn("postgres flat repo: create error: flat number taken", zap.Int("flat_id", flatID))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: create error: %w", domain.ErrFlat_NumberTaken)
+	}
 	if err != nil {
 		lg.Warn("postgres flat repo: create error", zap.Error(err))
 		return domain.Flat{}, fmt.Errorf("postgres flat repo: create error: %v", err.Error())
 	}
 
-	date := time.Now()
-	query = `update houses set update_flat_date=$1 where house_id=$2`
-	_, err = tx.Exec(ctx, query, date, createdFlat.HouseID)
+	err = insertCreationHistory(ctx, tx, &createdFlat, verdict)
+	if err != nil {
+		lg.Warn("postgres flat repo: create error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: create error: %v", err.Error())
+	}
+
+	err = insertApprovalNotifies(ctx, tx, &createdFlat)
 	if err != nil {
 		lg.Warn("postgres flat repo: create error", zap.Error(err))
 		return domain.Flat{}, fmt.Errorf("postgres flat repo: create error: %v", err.Error())
@@ -86,26 +267,81 @@ func (p *PostgresFlatRepo) DeleteByID(ctx context.Context, flatID int, houseID i
 	return nil
 }
 
-func (p *PostgresFlatRepo) Update(ctx context.Context, moderatorID uuid.UUID, newFlatData *domain.Flat, lg *zap.Logger) (domain.Flat, error) {
+func (p *PostgresFlatRepo) Update(ctx context.Context, moderatorID uuid.UUID, oldFlat *domain.Flat, newFlatData *domain.Flat,
+	decision *domain.ModerationDecision, lg *zap.Logger) (domain.Flat, error) {
 	lg.Info("postgres flat repo: update")
 
 	var (
 		flat domain.Flat
 	)
 
-	query := `select flat_id, house_id, user_id, price, rooms, status from update_status($1, $2, $3, $4)`
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		lg.Warn("postgres flat repo: update error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: update error: %v", err.Error())
+	}
 
-	rows := p.retryAdapter.QueryRow(ctx, query, newFlatData.Status,
-		newFlatData.ID, newFlatData.HouseID, moderatorID)
-	defer rows.Close()
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback(ctx)
+			if rollbackErr != nil {
+				err = fmt.Errorf("postgres flat repo: update error: %v", err.Error())
+			}
+		}
+	}()
+
+	// the transition was checked against oldFlat, so the row must still be in that state and version
+	query := `update flats set status=$1, moderator_id=$2, moderation_expires_at=$3, status_date=now(),
+			version=version + 1
+		where flat_id=$4 and house_id=$5 and status=$6 and moderator_id is not distinct from $7 and version=$8
+		returning ` + flatColumns
+	err = scanFlat(tx.QueryRow(ctx, query, newFlatData.Status, nullableUUID(newFlatData.ModeratorID),
+		nullableTime(newFlatData.ModerationExpiresAt), oldFlat.ID, oldFlat.HouseID, oldFlat.Status,
+		nullableUUID(oldFlat.ModeratorID), oldFlat.Version), &flat)
+	if errors.Is(err, pgx.ErrNoRows) {
+		lg.Warn("postgres flat repo: update error: flat was changed concurrently")
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: update error: %w", domain.ErrFlat_Conflict)
+	}
+	if err != nil {
+		lg.Warn("postgres flat repo: update error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: update error: %v", err.Error())
+	}
+
+	var comment string
+	if decision != nil {
+		comment = decision.Comment
+	}
+	err = insertStatusChange(ctx, tx, &flat, oldFlat.Status, moderatorID, comment)
+	if err != nil {
+		lg.Warn("postgres flat repo: update error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: update error: %v", err.Error())
+	}
+
+	if decision != nil {
+		err = insertDecision(ctx, tx, decision)
+		if err != nil {
+			lg.Warn("postgres flat repo: update error", zap.Error(err))
+			return domain.Flat{}, fmt.Errorf("postgres flat repo: update error: %v", err.Error())
+		}
+	}
+
+	err = insertApprovalNotifies(ctx, tx, &flat)
+	if err != nil {
+		lg.Warn("postgres flat repo: update error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: update error: %v", err.Error())
+	}
 
-	err := rows.Scan(&flat.ID, &flat.HouseID, &flat.UserID,
-		&flat.Price, &flat.Rooms, &flat.Status)
+	err = touchHouse(ctx, tx, flat.HouseID)
 	if err != nil {
 		lg.Warn("postgres flat repo: update error", zap.Error(err))
 		return domain.Flat{}, fmt.Errorf("postgres flat repo: update error: %v", err.Error())
 	}
 
+	if err = tx.Commit(ctx); err != nil {
+		lg.Error("postgres flat repo: update error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: update error: %v", err.Error())
+	}
+
 	return flat, nil
 }
 
@@ -113,12 +349,23 @@ func (p *PostgresFlatRepo) GetByID(ctx context.Context, flatID int, houseID int,
 	var flat domain.Flat
 	lg.Info("postgres flat repo: get by id")
 
-	query := `select flat_id, house_id, user_id, price, rooms, status
+	query := `select ` + flatColumns + `
 	from flats where flat_id=$1 and house_id=$2`
-	rows := p.retryAdapter.QueryRow(ctx, query, flatID, houseID)
+	rows, err := p.retryAdapter.Query(ctx, query, flatID, houseID)
+	if err != nil {
+		lg.Warn("postgres flat repo: get by id error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: get by id error: %v", err.Error())
+	}
 	defer rows.Close()
-	err := rows.Scan(&flat.ID, &flat.HouseID, &flat.UserID,
-		&flat.Price, &flat.Rooms, &flat.Status)
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			lg.Warn("postgres flat repo: get by id error", zap.Error(err))
+			return domain.Flat{}, fmt.Errorf("postgres flat repo: get by id error: %v", err.Error())
+		}
+		lg.Warn("postgres flat repo: get by id error: flat not found")
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: get by id error: %w", domain.ErrFlat_NotFound)
+	}
+	err = scanFlat(rows, &flat)
 	if err != nil {
 		lg.Warn("postgres flat repo: get by id error", zap.Error(err))
 		return domain.Flat{}, fmt.Errorf("postgres flat repo: get by id error: %v", err.Error())
@@ -130,7 +377,7 @@ func (p *PostgresFlatRepo) GetByID(ctx context.Context, flatID int, houseID int,
 func (p *PostgresFlatRepo) GetAll(ctx context.Context, offset int, limit int, lg *zap.Logger) ([]domain.Flat, error) {
 	lg.Info("postgres flat repo: get all")
 
-	query := `select flat_id, house_id, user_id, price, rooms, status from flats limit $1 offset $2`
+	query := `select ` + flatColumns + ` from flats limit $1 offset $2`
 	rows, err := p.retryAdapter.Query(ctx, query, limit, offset)
 	defer rows.Close()
 	if err != nil {
@@ -143,8 +390,7 @@ func (p *PostgresFlatRepo) GetAll(ctx context.Context, offset int, limit int, lg
 		flat  domain.Flat
 	)
 	for rows.Next() {
-		err = rows.Scan(&flat.ID, &flat.HouseID, &flat.UserID,
-			&flat.Price, &flat.Rooms, &flat.Status)
+		err = scanFlat(rows, &flat)
 		if err != nil {
 			lg.Warn("postgres flat repo: get all error: scan flat error", zap.Error(err))
 			continue
@@ -154,3 +400,508 @@ func (p *PostgresFlatRepo) GetAll(ctx context.Context, offset int, limit int, lg
 
 	return flats, err
 }
+
+func (p *PostgresFlatRepo) Search(ctx context.Context, filter *domain.FlatSearchFilter, lg *zap.Logger) ([]domain.Flat, error) {
+	lg.Info("postgres flat repo: search", zap.String("sort", filter.Sort), zap.Int("limit", filter.Limit))
+
+	builder := conditionBuilder{}
+	builder.add("f.status = $%d", domain.ApprovedStatus)
+	if filter.PriceMin > 0 {
+		builder.add("f.price >= $%d", filter.PriceMin)
+	}
+	if filter.PriceMax > 0 {
+		builder.add("f.price <= $%d", filter.PriceMax)
+	}
+	if filter.Rooms > 0 {
+		builder.add("f.rooms = $%d", filter.Rooms)
+	}
+	if filter.HouseID > 0 {
+		builder.add("f.house_id = $%d", filter.HouseID)
+	}
+	if filter.Developer != "" {
+		builder.add("f.house_id in (select house_id from houses where "+developerByNameCondition+")", filter.Developer)
+	}
+	if filter.YearMin > 0 {
+		builder.add("f.house_id in (select house_id from houses where construct_year >= $%d)", filter.YearMin)
+	}
+	if filter.YearMax > 0 {
+		builder.add("f.house_id in (select house_id from houses where construct_year <= $%d)", filter.YearMax)
+	}
+
+	order := "f.price, f.house_id, f.flat_id"
+	if filter.Sort == domain.SortByDate {
+		order = "f.create_flat_date desc, f.house_id desc, f.flat_id desc"
+		if filter.After != nil {
+			builder.add("(f.create_flat_date, f.house_id, f.flat_id) < ($%d, $%d, $%d)",
+				filter.After.Date, filter.After.HouseID, filter.After.FlatID)
+		}
+	} else if filter.After != nil {
+		builder.add("(f.price, f.house_id, f.flat_id) > ($%d, $%d, $%d)",
+			filter.After.Price, filter.After.HouseID, filter.After.FlatID)
+	}
+
+	query := fmt.Sprintf(`select %s, create_flat_date
+		from flats f
+		where %s
+		order by %s
+		limit $%d`, flatColumns, builder.where(), order, builder.arg(filter.Limit))
+	rows, err := p.retryAdapter.Query(ctx, query, builder.args...)
+	if err != nil {
+		lg.Warn("postgres flat repo: search error", zap.Error(err))
+		return nil, fmt.Errorf("postgres flat repo: search error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var flats []domain.Flat
+	for rows.Next() {
+		flat := domain.Flat{}
+		err = scanFlat(rows, &flat, &flat.CreateFlatDate)
+		if err != nil {
+			lg.Warn("postgres flat repo: search error: scan flat error", zap.Error(err))
+			continue
+		}
+		flats = append(flats, flat)
+	}
+
+	return flats, rows.Err()
+}
+
+func (p *PostgresFlatRepo) Edit(ctx context.Context, oldFlat *domain.Flat, newFlatData *domain.Flat,
+	edit *domain.FlatEdit, lg *zap.Logger) (domain.Flat, error) {
+	lg.Info("postgres flat repo: edit", zap.Int("flat_id", oldFlat.ID), zap.Int("house_id", oldFlat.HouseID))
+
+	var (
+		editedFlat domain.Flat
+	)
+
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		lg.Warn("postgres flat repo: edit error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: edit error: %v", err.Error())
+	}
+
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback(ctx)
+			if rollbackErr != nil {
+				err = fmt.Errorf("postgres flat repo: edit error: %v", err.Error())
+			}
+		}
+	}()
+
+	query := `update flats set price=$1, rooms=$2, total_area=$3, living_area=$4, floor=$5, floors=$6,
+				description=$7, layout=$8, balcony=$9, status=$10, moderator_id=null, moderation_expires_at=null,
+				status_date=(case when status=$10 then status_date else now() end), version=version + 1
+			where flat_id=$11 and house_id=$12 and user_id=$13 and status=$14 and version=$15
+			returning ` + flatColumns
+	err = scanFlat(tx.QueryRow(ctx, query, newFlatData.Price, newFlatData.Rooms,
+		newFlatData.TotalArea, newFlatData.LivingArea, newFlatData.Floor, newFlatData.Floors,
+		newFlatData.Description, newFlatData.Layout, newFlatData.Balcony, domain.CreatedStatus,
+		oldFlat.ID, oldFlat.HouseID, oldFlat.UserID, oldFlat.Status, oldFlat.Version), &editedFlat)
+	if errors.Is(err, pgx.ErrNoRows) {
+		lg.Warn("postgres flat repo: edit error: flat was changed concurrently")
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: edit error: %w", domain.ErrFlat_Conflict)
+	}
+	if err != nil {
+		lg.Warn("postgres flat repo: edit error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: edit error: %v", err.Error())
+	}
+
+	if oldFlat.Status != editedFlat.Status {
+		err = insertStatusChange(ctx, tx, &editedFlat, oldFlat.Status, edit.UserID, "")
+		if err != nil {
+			lg.Warn("postgres flat repo: edit error", zap.Error(err))
+			return domain.Flat{}, fmt.Errorf("postgres flat repo: edit error: %v", err.Error())
+		}
+	}
+
+	query = `insert into flat_edits(flat_id, house_id, user_id, changes, edit_date)
+			values ($1, $2, $3, $4, $5)`
+	_, err = tx.Exec(ctx, query, edit.FlatID, edit.HouseID, edit.UserID, edit.Changes, time.Now())
+	if err != nil {
+		lg.Warn("postgres flat repo: edit error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: edit error: %v", err.Error())
+	}
+
+	err = touchHouse(ctx, tx, editedFlat.HouseID)
+	if err != nil {
+		lg.Warn("postgres flat repo: edit error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: edit error: %v", err.Error())
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		lg.Error("postgres flat repo: edit error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: edit error: %v", err.Error())
+	}
+
+	return editedFlat, nil
+}
+
+func (p *PostgresFlatRepo) GetEdits(ctx context.Context, flatID int, houseID int, lg *zap.Logger) ([]domain.FlatEdit, error) {
+	lg.Info("postgres flat repo: get edits", zap.Int("flat_id", flatID), zap.Int("house_id", houseID))
+
+	query := `select id, flat_id, house_id, user_id, changes, edit_date
+		from flat_edits
+		where flat_id=$1 and house_id=$2
+		order by id desc`
+	rows, err := p.retryAdapter.Query(ctx, query, flatID, houseID)
+	if err != nil {
+		lg.Warn("postgres flat repo: get edits error", zap.Error(err))
+		return nil, fmt.Errorf("postgres flat repo: get edits error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var edits []domain.FlatEdit
+	for rows.Next() {
+		edit := domain.FlatEdit{}
+		err = rows.Scan(&edit.ID, &edit.FlatID, &edit.HouseID, &edit.UserID, &edit.Changes, &edit.EditDate)
+		if err != nil {
+			lg.Warn("postgres flat repo: get edits error: scan edit error", zap.Error(err))
+			continue
+		}
+		edits = append(edits, edit)
+	}
+
+	return edits, rows.Err()
+}
+
+func (p *PostgresFlatRepo) Archive(ctx context.Context, actorID uuid.UUID, flat *domain.Flat, lg *zap.Logger) (domain.Flat, error) {
+	lg.Info("postgres flat repo: archive", zap.Int("flat_id", flat.ID), zap.Int("house_id", flat.HouseID))
+
+	var archivedFlat domain.Flat
+
+	query := `with changed as (
+			update flats set status=$1,
+				archived_status=(case when status=$2 then $3 else status end),
+				deleted_at=$4,
+				moderator_id=null,
+				moderation_expires_at=null,
+				status_date=now(),
+				version=version + 1
+			where flat_id=$5 and house_id=$6 and status=$7 and version=$9
+			returning ` + flatColumns + `
+		), history as (
+			insert into flat_status_history(flat_id, house_id, from_status, to_status, actor_id)
+			select flat_id, house_id, $7, status, $8 from changed
+		), touched as (
+			update houses set update_flat_date=clock_timestamp()
+			where house_id in (select house_id from changed)
+		)
+		select ` + flatColumns + ` from changed`
+	rows, err := p.retryAdapter.Query(ctx, query, domain.ArchivedStatus, domain.ModeratingStatus,
+		domain.CreatedStatus, time.Now(), flat.ID, flat.HouseID, flat.Status, actorID, flat.Version)
+	if err != nil {
+		lg.Warn("postgres flat repo: archive error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: archive error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			lg.Warn("postgres flat repo: archive error", zap.Error(err))
+			return domain.Flat{}, fmt.Errorf("postgres flat repo: archive error: %v", err.Error())
+		}
+		lg.Warn("postgres flat repo: archive error: flat was changed concurrently")
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: archive error: %w", domain.ErrFlat_Conflict)
+	}
+	err = scanFlat(rows, &archivedFlat)
+	if err != nil {
+		lg.Warn("postgres flat repo: archive error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: archive error: %v", err.Error())
+	}
+
+	return archivedFlat, nil
+}
+
+func (p *PostgresFlatRepo) Restore(ctx context.Context, actorID uuid.UUID, flat *domain.Flat, lg *zap.Logger) (domain.Flat, error) {
+	lg.Info("postgres flat repo: restore", zap.Int("flat_id", flat.ID), zap.Int("house_id", flat.HouseID))
+
+	var restoredFlat domain.Flat
+
+	query := `with changed as (
+			update flats set status=archived_status, archived_status=null, deleted_at=null, status_date=now(),
+				version=version + 1
+			where flat_id=$1 and house_id=$2 and status=$3 and version=$5
+			returning ` + flatColumns + `
+		), history as (
+			insert into flat_status_history(flat_id, house_id, from_status, to_status, actor_id)
+			select flat_id, house_id, $3, status, $4 from changed
+		), touched as (
+			update houses set update_flat_date=clock_timestamp()
+			where house_id in (select house_id from changed)
+		), notified as (
+			insert into new_flats_outbox(flat_id, house_id, user_id, mail, status)
+			select c.flat_id, c.house_id, u.user_id, u.mail, $7
+			from changed c
+			join subscribers s on s.house_id = c.house_id
+			join users u on u.user_id = s.user_id
+			where c.status = $6
+			on conflict (flat_id, house_id, user_id) do nothing
+		)
+		select ` + flatColumns + ` from changed`
+	rows, err := p.retryAdapter.Query(ctx, query, flat.ID, flat.HouseID, domain.ArchivedStatus, actorID, flat.Version,
+		domain.ApprovedStatus, domain.NoSendedNotifyStatus)
+	if err != nil {
+		lg.Warn("postgres flat repo: restore error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: restore error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			lg.Warn("postgres flat repo: restore error", zap.Error(err))
+			return domain.Flat{}, fmt.Errorf("postgres flat repo: restore error: %v", err.Error())
+		}
+		lg.Warn("postgres flat repo: restore error: flat was changed concurrently")
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: restore error: %w", domain.ErrFlat_Conflict)
+	}
+	err = scanFlat(rows, &restoredFlat)
+	if err != nil {
+		lg.Warn("postgres flat repo: restore error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres flat repo: restore error: %v", err.Error())
+	}
+
+	return restoredFlat, nil
+}
+
+func (p *PostgresFlatRepo) GetLastDecision(ctx context.Context, flatID int, houseID int, lg *zap.Logger) (*domain.ModerationDecision, error) {
+	lg.Info("postgres flat repo: get last decision", zap.Int("flat_id", flatID), zap.Int("house_id", houseID))
+
+	query := `select id, flat_id, house_id, moderator_id, decision, reason_code, comment, decision_date
+		from moderation_decisions
+		where flat_id=$1 and house_id=$2
+		order by id desc
+		limit 1`
+	rows, err := p.retryAdapter.Query(ctx, query, flatID, houseID)
+	if err != nil {
+		lg.Warn("postgres flat repo: get last decision error", zap.Error(err))
+		return nil, fmt.Errorf("postgres flat repo: get last decision error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var (
+		decision    domain.ModerationDecision
+		moderatorID *uuid.UUID
+	)
+	err = rows.Scan(&decision.ID, &decision.FlatID, &decision.HouseID, &moderatorID,
+		&decision.Decision, &decision.ReasonCode, &decision.Comment, &decision.DecisionDate)
+	if err != nil {
+		lg.Warn("postgres flat repo: get last decision error", zap.Error(err))
+		return nil, fmt.Errorf("postgres flat repo: get last decision error: %v", err.Error())
+	}
+	if moderatorID != nil {
+		decision.ModeratorID = *moderatorID
+	}
+
+	return &decision, nil
+}
+
+func (p *PostgresFlatRepo) GetStatusHistory(ctx context.Context, flatID int, houseID int, lg *zap.Logger) ([]domain.FlatStatusChange, error) {
+	lg.Info("postgres flat repo: get status history", zap.Int("flat_id", flatID), zap.Int("house_id", houseID))
+
+	query := `select id, flat_id, house_id, coalesce(from_status::text, ''), to_status, actor_id, comment, change_date
+		from flat_status_history
+		where flat_id=$1 and house_id=$2
+		order by id`
+	rows, err := p.retryAdapter.Query(ctx, query, flatID, houseID)
+	if err != nil {
+		lg.Warn("postgres flat repo: get status history error", zap.Error(err))
+		return nil, fmt.Errorf("postgres flat repo: get status history error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var history []domain.FlatStatusChange
+	for rows.Next() {
+		var (
+			change  domain.FlatStatusChange
+			actorID *uuid.UUID
+		)
+		err = rows.Scan(&change.ID, &change.FlatID, &change.HouseID, &change.FromStatus, &change.ToStatus,
+			&actorID, &change.Comment, &change.ChangeDate)
+		if err != nil {
+			lg.Warn("postgres flat repo: get status history error: scan change error", zap.Error(err))
+			continue
+		}
+		if actorID != nil {
+			change.ActorID = *actorID
+		}
+		history = append(history, change)
+	}
+
+	return history, rows.Err()
+}
+
+func (p *PostgresFlatRepo) GetByOwner(ctx context.Context, userID uuid.UUID, lg *zap.Logger) ([]domain.OwnerFlat, error) {
+	lg.Info("postgres flat repo: get by owner", zap.String("user_id", userID.String()))
+
+	// decision columns are aliased so they don't clash with the flat columns of the same name
+	query := `select ` + flatColumns + `, d.decision_id, d.decision_moderator_id, d.decision,
+			d.reason_code, d.comment, d.decision_date
+		from flats
+		left join lateral (
+			select id as decision_id, moderator_id as decision_moderator_id, decision, reason_code, comment, decision_date
+			from moderation_decisions
+			where moderation_decisions.flat_id = flats.flat_id and moderation_decisions.house_id = flats.house_id
+			order by id desc
+			limit 1
+		) d on true
+		where user_id=$1
+		order by house_id, flat_id`
+	rows, err := p.retryAdapter.Query(ctx, query, userID)
+	if err != nil {
+		lg.Warn("postgres flat repo: get by owner error", zap.Error(err))
+		return nil, fmt.Errorf("postgres flat repo: get by owner error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var flats []domain.OwnerFlat
+	for rows.Next() {
+		var (
+			ownerFlat    domain.OwnerFlat
+			decisionID   *int
+			moderatorID  *uuid.UUID
+			decision     *string
+			reasonCode   *string
+			comment      *string
+			decisionDate *time.Time
+		)
+		err = scanFlat(rows, &ownerFlat.Flat, &decisionID, &moderatorID, &decision, &reasonCode, &comment, &decisionDate)
+		if err != nil {
+			lg.Warn("postgres flat repo: get by owner error: scan flat error", zap.Error(err))
+			continue
+		}
+		if decisionID != nil {
+			ownerFlat.LastDecision = &domain.ModerationDecision{
+				ID:           *decisionID,
+				FlatID:       ownerFlat.Flat.ID,
+				HouseID:      ownerFlat.Flat.HouseID,
+				Decision:     *decision,
+				ReasonCode:   *reasonCode,
+				Comment:      *comment,
+				DecisionDate: *decisionDate,
+			}
+			if moderatorID != nil {
+				ownerFlat.LastDecision.ModeratorID = *moderatorID
+			}
+		}
+		flats = append(flats, ownerFlat)
+	}
+
+	return flats, rows.Err()
+}
+
+// GetHousePriceStats returns the median price over the approved flats of the house.
+func (p *PostgresFlatRepo) GetHousePriceStats(ctx context.Context, houseID int, lg *zap.Logger) (domain.HousePriceStats, error) {
+	lg.Info("postgres flat repo: get house price stats", zap.Int("house_id", houseID))
+
+	var stats domain.HousePriceStats
+
+	query := `select coalesce(percentile_cont(0.5) within group (order by price), 0), count(*)
+		from flats
+		where house_id=$1 and status=$2`
+	rows, err := p.retryAdapter.Query(ctx, query, houseID, domain.ApprovedStatus)
+	if err != nil {
+		lg.Warn("postgres flat repo: get house price stats error", zap.Error(err))
+		return domain.HousePriceStats{}, fmt.Errorf("postgres flat repo: get house price stats error: %v", err.Error())
+	}
+	defer rows.Close()
+	if rows.Next() {
+		err = rows.Scan(&stats.MedianPrice, &stats.Flats)
+		if err != nil {
+			lg.Warn("postgres flat repo: get house price stats error", zap.Error(err))
+			return domain.HousePriceStats{}, fmt.Errorf("postgres flat repo: get house price stats error: %v", err.Error())
+		}
+	}
+
+	return stats, rows.Err()
+}
+
+// FindDuplicate returns the number of the earliest listing the flat likely duplicates, 0 if there is none.
+// For a stored flat only listings created before it are considered.
+func (p *PostgresFlatRepo) FindDuplicate(ctx context.Context, flat *domain.Flat, lg *zap.Logger) (int, error) {
+	lg.Info("postgres flat repo: find duplicate", zap.Int("flat_id", flat.ID), zap.Int("house_id", flat.HouseID))
+
+	query := `select flat_id from flats
+		where house_id=$1 and user_id=$2 and rooms=$3 and price between $4 and $5
+			and ($6::numeric = 0 or total_area = 0 or abs(total_area - $6::numeric) <= $7::numeric)
+			and ($8 = 0 or floor = 0 or floor = $8)
+			and status != $9 and flat_id != $10
+			and ($11::timestamp is null or (create_flat_date, flat_id) < ($11::timestamp, $10))
+		order by create_flat_date, flat_id
+		limit 1`
+	priceDelta := int(float64(flat.Price) * domain.DuplicatePriceBand)
+	rows, err := p.retryAdapter.Query(ctx, query, flat.HouseID, flat.UserID, flat.Rooms,
+		flat.Price-priceDelta, flat.Price+priceDelta, flat.TotalArea, domain.DuplicateAreaTolerance,
+		flat.Floor, domain.ArchivedStatus, flat.ID, nullableTime(flat.CreateFlatDate))
+	if err != nil {
+		lg.Warn("postgres flat repo: find duplicate error", zap.Error(err))
+		return 0, fmt.Errorf("postgres flat repo: find duplicate error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var originalID int
+	if rows.Next() {
+		err = rows.Scan(&originalID)
+		if err != nil {
+			lg.Warn("postgres flat repo: find duplicate error", zap.Error(err))
+			return 0, fmt.Errorf("postgres flat repo: find duplicate error: %v", err.Error())
+		}
+	}
+
+	return originalID, rows.Err()
+}
+
+// GetUnlinkedBatch pages through flats not marked as duplicates in (house_id, flat_id) order.
+func (p *PostgresFlatRepo) GetUnlinkedBatch(ctx context.Context, afterHouseID int, afterFlatID int, limit int,
+	lg *zap.Logger) ([]domain.Flat, error) {
+	lg.Info("postgres flat repo: get unlinked batch", zap.Int("house_id", afterHouseID), zap.Int("flat_id", afterFlatID))
+
+	query := `select ` + flatColumns + `, create_flat_date
+		from flats
+		where duplicate_of is null and (house_id, flat_id) > ($1, $2)
+		order by house_id, flat_id
+		limit $3`
+	rows, err := p.retryAdapter.Query(ctx, query, afterHouseID, afterFlatID, limit)
+	if err != nil {
+		lg.Warn("postgres flat repo: get unlinked batch error", zap.Error(err))
+		return nil, fmt.Errorf("postgres flat repo: get unlinked batch error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var flats []domain.Flat
+	for rows.Next() {
+		var flat domain.Flat
+		err = scanFlat(rows, &flat, &flat.CreateFlatDate)
+		if err != nil {
+			lg.Warn("postgres flat repo: get unlinked batch error: scan flat error", zap.Error(err))
+			return nil, fmt.Errorf("postgres flat repo: get unlinked batch error: %v", err.Error())
+		}
+		flats = append(flats, flat)
+	}
+
+	return flats, rows.Err()
+}
+
+// SetDuplicateOf links an existing flat to its original. The version is left as is:
+// the link is derived data and must not fail a client's If-Match. The house list shows the link,
+// so its update date moves on, like on any other flat change.
+func (p *PostgresFlatRepo) SetDuplicateOf(ctx context.Context, flat *domain.Flat, duplicateOf int, lg *zap.Logger) error {
+	lg.Info("postgres flat repo: set duplicate of", zap.Int("flat_id", flat.ID), zap.Int("duplicate_of", duplicateOf))
+
+	query := `with changed as (
+			update flats set duplicate_of=$1
+			where flat_id=$2 and house_id=$3 and duplicate_of is null
+			returning house_id
+		)
+		update houses set update_flat_date=clock_timestamp()
+		where house_id in (select house_id from changed)`
+	// retryAdapter.Exec drops the error of the last attempt, and a lost write would leave the flat unlinked silently
+	_, err := p.db.Exec(ctx, query, duplicateOf, flat.ID, flat.HouseID)
+	if err != nil {
+		lg.Warn("postgres flat repo: set duplicate of error", zap.Error(err))
+		return fmt.Errorf("postgres flat repo: set duplicate of error: %v", err.Error())
+	}
+
+	return nil
+}
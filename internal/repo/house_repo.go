@@ -3,11 +3,13 @@ package repo
 import (
 	"avito-test-task/internal/domain"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
+	"strconv"
 )
 
 type PostgresHouseRepo struct {
@@ -22,74 +24,198 @@ func NewPostgresHouseRepo(db *pgxpool.Pool, retryAdapter IPostgresRetryAdapter)
 	}
 }
 
+const houseColumns = `house_id, address, construct_year, coalesce(developer_id, 0),
+	coalesce((select name from developers where developers.developer_id = houses.developer_id), ''),
+	create_house_date, update_flat_date, version, latitude, longitude`
+
+// houseFlatCounts joins the number of listed and approved flats of every house as c.flats and c.approved_flats.
+// The approved number comes from the counters kept by the flats_count_approved trigger.
+const houseFlatCounts = `cross join lateral (
+			select (select count(*) from flats
+					where flats.house_id = houses.house_id and flats.status != 'archived') as flats,
+				(select coalesce(sum(pc.flats), 0) from house_price_counts pc
+					where pc.house_id = houses.house_id) as approved_flats
+		) c`
+
+// hasApprovedFlatsCondition probes the approved flat counters of a house instead of counting its flats.
+const hasApprovedFlatsCondition = `exists (select 1 from house_price_counts pc
+			where pc.house_id = houses.house_id and pc.flats > 0)`
+
+func scanHouse(row pgx.Row, house *domain.House, extra ...any) error {
+	dest := []any{&house.HouseID, &house.Address, &house.ConstructYear, &house.DeveloperID,
+		&house.Developer, &house.CreateHouseDate, &house.UpdateFlatDate, &house.Version,
+		&house.Latitude, &house.Longitude}
+	return row.Scan(append(dest, extra...)...)
+}
+
 func (p *PostgresHouseRepo) Create(ctx context.Context, house *domain.House, lg *zap.Logger) (domain.House, error) {
-	lg.Info("create house", zap.Int("house_id", house.HouseID))
+	lg.Info("postgres house repo: create", zap.Int("developer_id", house.DeveloperID))
 
 	var createdHouse domain.House
-	query := `insert into houses(address, construct_year, developer, create_house_date, update_flat_date)
-	values ($1, $2, $3, $4, $5) returning *`
-	rows := p.retryAdapter.QueryRow(ctx, query,
+	query := `insert into houses(address, construct_year, developer_id, create_house_date, update_flat_date,
+		latitude, longitude, normalized_address)
+	values ($1, $2, $3, $4, $5, $6, $7, $8) returning ` + houseColumns
+	rows, err := p.retryAdapter.Query(ctx, query,
 		house.Address, house.ConstructYear,
-		house.Developer, house.CreateHouseDate,
-		house.UpdateFlatDate)
+		house.DeveloperID, house.CreateHouseDate,
+		house.UpdateFlatDate, house.Latitude, house.Longitude, house.NormalizedAddress)
+	if err != nil {
+		lg.Warn("postgres house repo: create error", zap.Error(err))
+		return domain.House{}, fmt.Errorf("postgres house repo: create error: %v", err.Error())
+	}
 	defer rows.Close()
-	err := rows.Scan(&createdHouse.HouseID, &createdHouse.Address,
-		&createdHouse.ConstructYear, &createdHouse.Developer,
-		&createdHouse.CreateHouseDate, &createdHouse.UpdateFlatDate)
+	if !rows.Next() {
+		err = rows.Err()
+		if isForeignKeyViolation(err) {
+			lg.Warn("postgres house repo: create error: developer not found")
+			return domain.House{}, fmt.Errorf("postgres house repo: create error: %w", domain.ErrDeveloper_NotFound)
+		}
+		lg.Warn("postgres house repo: create error", zap.Error(err))
+		return domain.House{}, fmt.Errorf("postgres house repo: create error: %v", err)
+	}
+	err = scanHouse(rows, &createdHouse)
 	if err != nil {
-		lg.Warn("postgres house create error", zap.Error(err))
-		return domain.House{}, err
+		lg.Warn("postgres house repo: create error", zap.Error(err))
+		return domain.House{}, fmt.Errorf("postgres house repo: create error: %v", err.Error())
 	}
 
 	return createdHouse, nil
 }
 
-func (p *PostgresHouseRepo) DeleteByID(ctx context.Context, id int, lg *zap.Logger) error {
-	lg.Info("delete house", zap.Int("house_id", id))
+// DeleteByID removes an empty house together with its subscriptions; houses that still have flats are kept.
+func (p *PostgresHouseRepo) DeleteByID(ctx context.Context, house *domain.House, lg *zap.Logger) error {
+	lg.Info("postgres house repo: delete by id", zap.Int("house_id", house.HouseID))
 
-	query := `delete from houses where id=$1`
-	_, err := p.retryAdapter.Exec(ctx, query, id)
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		lg.Warn("postgres house delete error", zap.Error(err))
-		return err
+		lg.Warn("postgres house repo: delete by id error", zap.Error(err))
+		return fmt.Errorf("postgres house repo: delete by id error: %v", err.Error())
+	}
+
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback(ctx)
+			if rollbackErr != nil {
+				err = fmt.Errorf("postgres house repo: delete by id error: %v", err.Error())
+			}
+		}
+	}()
+
+	// the row lock also blocks flats from being added to the house until commit
+	var version int
+	query := `select version from houses where house_id=$1 for update`
+	err = tx.QueryRow(ctx, query, house.HouseID).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		lg.Warn("postgres house repo: delete by id error: house not found")
+		return fmt.Errorf("postgres house repo: delete by id error: %w", domain.ErrHouse_NotFound)
+	}
+	if err != nil {
+		lg.Warn("postgres house repo: delete by id error", zap.Error(err))
+		return fmt.Errorf("postgres house repo: delete by id error: %v", err.Error())
+	}
+	if version != house.Version {
+		err = domain.ErrHouse_Conflict
+		lg.Warn("postgres house repo: delete by id error: house was changed concurrently")
+		return fmt.Errorf("postgres house repo: delete by id error: %w", err)
+	}
+
+	var hasFlats bool
+	query = `select exists(select 1 from flats where house_id=$1)`
+	err = tx.QueryRow(ctx, query, house.HouseID).Scan(&hasFlats)
+	if err != nil {
+		lg.Warn("postgres house repo: delete by id error", zap.Error(err))
+		return fmt.Errorf("postgres house repo: delete by id error: %v", err.Error())
+	}
+	if hasFlats {
+		err = domain.ErrHouse_HasFlats
+		lg.Warn("postgres house repo: delete by id error: house has flats")
+		return fmt.Errorf("postgres house repo: delete by id error: %w", err)
+	}
+
+	for _, query = range []string{
+		`delete from new_flats_outbox where house_id=$1`,
+		`delete from subscribers where house_id=$1`,
+		`delete from house_price_counts where house_id=$1`,
+		`delete from houses where house_id=$1`,
+	} {
+		_, err = tx.Exec(ctx, query, house.HouseID)
+		if err != nil {
+			lg.Warn("postgres house repo: delete by id error", zap.Error(err))
+			return fmt.Errorf("postgres house repo: delete by id error: %v", err.Error())
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		lg.Warn("postgres house repo: delete by id error", zap.Error(err))
+		return fmt.Errorf("postgres house repo: delete by id error: %v", err.Error())
 	}
 
 	return nil
 }
 
-func (p *PostgresHouseRepo) Update(ctx context.Context, newHouseData *domain.House, lg *zap.Logger) error {
-	lg.Info("update house", zap.Int("house_id", newHouseData.HouseID))
+func (p *PostgresHouseRepo) Update(ctx context.Context, oldHouse *domain.House, newHouseData *domain.House,
+	lg *zap.Logger) (domain.House, error) {
+	lg.Info("postgres house repo: update", zap.Int("house_id", oldHouse.HouseID))
 
-	query := `update houses set house_id=$1,
-                  				address=$2,
-                  				construct_year=$3,
-                  				developer=$4,
-                  				create_house_date=$5,
-                  				update_flat_date=$6`
-	_, err := p.retryAdapter.Exec(ctx, query, newHouseData.HouseID, newHouseData.Address,
-		newHouseData.ConstructYear, newHouseData.Developer,
-		newHouseData.CreateHouseDate, newHouseData.UpdateFlatDate)
+	var house domain.House
+	query := `update houses set address=$1, construct_year=$2, developer_id=$3, latitude=$4, longitude=$5,
+			normalized_address=$6, version=version + 1
+		where house_id=$7 and version=$8
+		returning ` + houseColumns
+	rows, err := p.retryAdapter.Query(ctx, query, newHouseData.Address, newHouseData.ConstructYear,
+		newHouseData.DeveloperID, newHouseData.Latitude, newHouseData.Longitude, newHouseData.NormalizedAddress,
+		oldHouse.HouseID, oldHouse.Version)
+	if err != nil {
+		lg.Warn("postgres house repo: update error", zap.Error(err))
+		return domain.House{}, fmt.Errorf("postgres house repo: update error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		err = rows.Err()
+		if isForeignKeyViolation(err) {
+			lg.Warn("postgres house repo: update error: developer not found")
+			return domain.House{}, fmt.Errorf("postgres house repo: update error: %w", domain.ErrDeveloper_NotFound)
+		}
+		if err != nil {
+			lg.Warn("postgres house repo: update error", zap.Error(err))
+			return domain.House{}, fmt.Errorf("postgres house repo: update error: %v", err.Error())
+		}
+		lg.Warn("postgres house repo: update error: house was changed concurrently")
+		return domain.House{}, fmt.Errorf("postgres house repo: update error: %w", domain.ErrHouse_Conflict)
+	}
+	err = scanHouse(rows, &house)
 	if err != nil {
-		lg.Warn("postgres house update error", zap.Error(err))
-		return err
+		lg.Warn("postgres house repo: update error", zap.Error(err))
+		return domain.House{}, fmt.Errorf("postgres house repo: update error: %v", err.Error())
 	}
 
-	return nil
+	return house, nil
 }
 
 func (p *PostgresHouseRepo) GetByID(ctx context.Context, id int, lg *zap.Logger) (domain.House, error) {
-	lg.Info("get house by id", zap.Int("id", id))
+	lg.Info("postgres house repo: get by id", zap.Int("house_id", id))
 	var house domain.House
 
-	query := `select * from houses where house_id=$1`
-	rows := p.retryAdapter.QueryRow(ctx, query, id)
+	query := `select ` + houseColumns + ` from houses where house_id=$1`
+	rows, err := p.retryAdapter.Query(ctx, query, id)
+	if err != nil {
+		lg.Warn("postgres house repo: get by id error", zap.Error(err))
+		return domain.House{}, fmt.Errorf("postgres house repo: get by id error: %v", err.Error())
+	}
 	defer rows.Close()
-	err := rows.Scan(&house.HouseID, &house.Address,
-		&house.ConstructYear, &house.Developer,
-		&house.CreateHouseDate, &house.UpdateFlatDate)
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			lg.Warn("postgres house repo: get by id error", zap.Error(err))
+			return domain.House{}, fmt.Errorf("postgres house repo: get by id error: %v", err.Error())
+		}
+		lg.Warn("postgres house repo: get by id error: house not found")
+		return domain.House{}, fmt.Errorf("postgres house repo: get by id error: %w", domain.ErrHouse_NotFound)
+	}
+	err = scanHouse(rows, &house)
 	if err != nil {
-		lg.Warn("postgres house get by id error", zap.Error(err))
-		return domain.House{}, err
+		lg.Warn("postgres house repo: get by id error", zap.Error(err))
+		return domain.House{}, fmt.Errorf("postgres house repo: get by id error: %v", err.Error())
 	}
 
 	return house, nil
@@ -98,7 +224,7 @@ func (p *PostgresHouseRepo) GetByID(ctx context.Context, id int, lg *zap.Logger)
 func (p *PostgresHouseRepo) GetAll(ctx context.Context, offset int, limit int, lg *zap.Logger) ([]domain.House, error) {
 	lg.Info("get houses", zap.Int("offset", offset), zap.Int("limit", limit))
 
-	query := `select * from houses limit $1 offset $2`
+	query := `select ` + houseColumns + ` from houses limit $1 offset $2`
 	rows, err := p.retryAdapter.Query(ctx, query, limit, offset)
 	defer rows.Close()
 	if err != nil {
@@ -111,8 +237,7 @@ func (p *PostgresHouseRepo) GetAll(ctx context.Context, offset int, limit int, l
 		house  domain.House
 	)
 	for rows.Next() {
-		err = rows.Scan(&house.HouseID, &house.Address, &house.ConstructYear,
-			&house.Developer, &house.CreateHouseDate, &house.UpdateFlatDate)
+		err = scanHouse(rows, &house)
 		if err != nil {
 			lg.Warn("postgres house get all error: scan house error")
 			continue
@@ -123,38 +248,59 @@ func (p *PostgresHouseRepo) GetAll(ctx context.Context, offset int, limit int, l
 	return houses, err
 }
 
-func (p *PostgresHouseRepo) GetFlatsByHouseID(ctx context.Context, id int, status string, lg *zap.Logger) ([]domain.Flat, error) {
-	lg.Info("get flats by house id", zap.Int("house_id", id))
+func (p *PostgresHouseRepo) GetFlatsByHouseID(ctx context.Context, id int, status string,
+	filter *domain.FlatsByHouseFilter, lg *zap.Logger) ([]domain.Flat, error) {
+	lg.Info("get flats by house id", zap.Int("house_id", id), zap.String("sort", filter.Sort))
 
-	query := ``
-	var (
-		rows pgx.Rows
-		err  error
-	)
-	if status == domain.ModeratingStatus {
-		query = `select flat_id, houses.house_id, price, rooms, status 
-			from flats join houses
-			on flats.house_id = houses.house_id
-			where houses.house_id=$1 and flats.status=$2`
-		rows, err = p.retryAdapter.Query(ctx, query, id, status)
-	} else {
-		query = `select flat_id, houses.house_id, price, rooms, status 
-			from flats join houses
-			on flats.house_id = houses.house_id
-			where houses.house_id=$1`
-		rows, err = p.retryAdapter.Query(ctx, query, id)
+	builder := conditionBuilder{}
+	builder.add("house_id = $%d", id)
+	if status != domain.AnyStatus {
+		builder.add("status = $%d", status)
+	}
+	if filter.Rooms > 0 {
+		builder.add("rooms = $%d", filter.Rooms)
+	}
+	if filter.PriceMin > 0 {
+		builder.add("price >= $%d", filter.PriceMin)
+	}
+	if filter.PriceMax > 0 {
+		builder.add("price <= $%d", filter.PriceMax)
 	}
 
-	defer rows.Close()
+	order := "flat_id"
+	switch filter.Sort {
+	case domain.SortByPrice:
+		order = "price, flat_id"
+		if filter.After != nil {
+			builder.add("(price, flat_id) > ($%d, $%d)", filter.After.Value, filter.After.FlatID)
+		}
+	case domain.SortByRooms:
+		order = "rooms, flat_id"
+		if filter.After != nil {
+			builder.add("(rooms, flat_id) > ($%d, $%d)", filter.After.Value, filter.After.FlatID)
+		}
+	default:
+		if filter.After != nil {
+			builder.add("flat_id > $%d", filter.After.FlatID)
+		}
+	}
+
+	query := fmt.Sprintf(`select %s
+		from flats
+		where %s
+		order by %s
+		limit $%d`, flatColumns, builder.where(), order, builder.arg(filter.Limit))
+	rows, err := p.retryAdapter.Query(ctx, query, builder.args...)
 	if err != nil {
 		lg.Warn("postgres house repo: get flats by house id", zap.Error(err))
 		return nil, fmt.Errorf("postgres house repo: get flats by house id: %v", err.Error())
 	}
+	defer rows.Close()
 
 	var flats []domain.Flat
 	for rows.Next() {
 		flat := domain.Flat{}
-		err = rows.Scan(&flat.ID, &flat.HouseID, &flat.Price, &flat.Rooms, &flat.Status)
+		err = scanFlat(rows, &flat)
 		if err != nil {
 			lg.Warn("postgres house repo: get all error: scan house error", zap.Error(err))
 			continue
@@ -162,7 +308,7 @@ func (p *PostgresHouseRepo) GetFlatsByHouseID(ctx context.Context, id int, statu
 		flats = append(flats, flat)
 	}
 
-	return flats, err
+	return flats, rows.Err()
 }
 
 func (p *PostgresHouseRepo) SubscribeByID(ctx context.Context, houseID int, userID uuid.UUID, lg *zap.Logger) error {
@@ -177,3 +323,286 @@ func (p *PostgresHouseRepo) SubscribeByID(ctx context.Context, houseID int, user
 
 	return nil
 }
+
+// UnsubscribeByID removes the subscription together with the notifications not sent to the user yet.
+func (p *PostgresHouseRepo) UnsubscribeByID(ctx context.Context, houseID int, userID uuid.UUID, lg *zap.Logger) error {
+	lg.Info("postgres house repo: unsubscribe by id", zap.Int("house_id", houseID))
+
+	query := `with removed as (
+			delete from subscribers where user_id=$1 and house_id=$2
+			returning house_id
+		), pending as (
+			delete from new_flats_outbox where user_id=$1 and house_id=$2 and status=$3
+		)
+		select house_id from removed`
+	rows, err := p.retryAdapter.Query(ctx, query, userID, houseID, domain.NoSendedNotifyStatus)
+	if err != nil {
+		lg.Warn("postgres house repo: unsubscribe by id error", zap.Error(err))
+		return fmt.Errorf("postgres house repo: unsubscribe by id error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			lg.Warn("postgres house repo: unsubscribe by id error", zap.Error(err))
+			return fmt.Errorf("postgres house repo: unsubscribe by id error: %v", err.Error())
+		}
+		lg.Warn("postgres house repo: unsubscribe by id error: subscription not found")
+		return fmt.Errorf("postgres house repo: unsubscribe by id error: %w", domain.ErrSubscription_NotFound)
+	}
+
+	return nil
+}
+
+func (p *PostgresHouseRepo) GetSubscriptions(ctx context.Context, userID uuid.UUID, lg *zap.Logger) ([]domain.Subscription, error) {
+	lg.Info("postgres house repo: get subscriptions", zap.String("user_id", userID.String()))
+
+	query := `select s.house_id, h.address, s.subscribe_date
+		from subscribers s
+		join houses h on h.house_id = s.house_id
+		where s.user_id=$1
+		order by s.subscribe_date desc, s.house_id`
+	rows, err := p.retryAdapter.Query(ctx, query, userID)
+	if err != nil {
+		lg.Warn("postgres house repo: get subscriptions error", zap.Error(err))
+		return nil, fmt.Errorf("postgres house repo: get subscriptions error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var subscriptions []domain.Subscription
+	for rows.Next() {
+		var subscription domain.Subscription
+		err = rows.Scan(&subscription.HouseID, &subscription.Address, &subscription.SubscribeDate)
+		if err != nil {
+			lg.Warn("postgres house repo: get subscriptions error: scan subscription error", zap.Error(err))
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// Search lists houses with the newest flats first; counts skip archived flats.
+func (p *PostgresHouseRepo) Search(ctx context.Context, filter *domain.HouseSearchFilter, lg *zap.Logger) ([]domain.HouseListItem, error) {
+	lg.Info("postgres house repo: search", zap.Int("limit", filter.Limit))
+
+	builder := conditionBuilder{}
+	if filter.DeveloperID > 0 {
+		builder.add("developer_id = $%d", filter.DeveloperID)
+	}
+	if filter.Developer != "" {
+		builder.add(developerByNameCondition, filter.Developer)
+	}
+	if filter.YearMin > 0 {
+		builder.add("construct_year >= $%d", filter.YearMin)
+	}
+	if filter.YearMax > 0 {
+		builder.add("construct_year <= $%d", filter.YearMax)
+	}
+	if filter.Address != "" {
+		builder.add("address ilike '%%' || $%d || '%%'", likeEscaper.Replace(filter.Address))
+	}
+	if filter.HasApprovedFlats {
+		builder.add(hasApprovedFlatsCondition)
+	}
+	if filter.Box != nil {
+		builder.add("latitude between $%d and $%d", filter.Box.MinLat, filter.Box.MaxLat)
+		if filter.Box.MinLon <= filter.Box.MaxLon {
+			builder.add("longitude between $%d and $%d", filter.Box.MinLon, filter.Box.MaxLon)
+		} else {
+			builder.add("(longitude >= $%d or longitude <= $%d)", filter.Box.MinLon, filter.Box.MaxLon)
+		}
+	}
+	if filter.After != nil {
+		builder.add("(update_flat_date, house_id) < ($%d, $%d)", filter.After.Date, filter.After.HouseID)
+	}
+
+	// flats are counted only for the page, after the filters and the limit
+	query := fmt.Sprintf(`select `+houseColumns+`, c.flats, c.approved_flats
+		from (
+			select * from houses
+			where %s
+			order by update_flat_date desc, house_id desc
+			limit $%d
+		) houses
+		`+houseFlatCounts+`
+		order by update_flat_date desc, house_id desc`, builder.where(), builder.arg(filter.Limit))
+	rows, err := p.retryAdapter.Query(ctx, query, builder.args...)
+	if err != nil {
+		lg.Warn("postgres house repo: search error", zap.Error(err))
+		return nil, fmt.Errorf("postgres house repo: search error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var houses []domain.HouseListItem
+	for rows.Next() {
+		var item domain.HouseListItem
+		err = scanHouse(rows, &item.House, &item.Flats, &item.ApprovedFlats)
+		if err != nil {
+			lg.Warn("postgres house repo: search error: scan house error", zap.Error(err))
+			continue
+		}
+		houses = append(houses, item)
+	}
+
+	return houses, rows.Err()
+}
+
+// Nearby lists houses within filter.Radius meters of the point, closest first.
+func (p *PostgresHouseRepo) Nearby(ctx context.Context, filter *domain.HouseNearbyFilter, lg *zap.Logger) ([]domain.HouseListItem, error) {
+	lg.Info("postgres house repo: nearby", zap.Float64("radius", filter.Radius), zap.Int("limit", filter.Limit))
+
+	// earth_box is a cheap bounding cube for the gist index, earth_distance cuts its corners
+	query := `select ` + houseColumns + `, c.flats, c.approved_flats, d.distance
+		from houses
+		cross join lateral (
+			select earth_distance(ll_to_earth($1, $2), ll_to_earth(latitude, longitude)) as distance
+		) d
+		` + houseFlatCounts + `
+		where latitude is not null
+			and earth_box(ll_to_earth($1, $2), $3) @> ll_to_earth(latitude, longitude)
+			and d.distance <= $3
+		order by d.distance, house_id
+		limit $4`
+	rows, err := p.retryAdapter.Query(ctx, query, filter.Latitude, filter.Longitude, filter.Radius, filter.Limit)
+	if err != nil {
+		lg.Warn("postgres house repo: nearby error", zap.Error(err))
+		return nil, fmt.Errorf("postgres house repo: nearby error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var houses []domain.HouseListItem
+	for rows.Next() {
+		var item domain.HouseListItem
+		err = scanHouse(rows, &item.House, &item.Flats, &item.ApprovedFlats, &item.Distance)
+		if err != nil {
+			lg.Warn("postgres house repo: nearby error: scan house error", zap.Error(err))
+			continue
+		}
+		houses = append(houses, item)
+	}
+
+	return houses, rows.Err()
+}
+
+// SearchByAddress ranks houses by how well the normalized query matches a part of their normalized address.
+func (p *PostgresHouseRepo) SearchByAddress(ctx context.Context, filter *domain.HouseAddressFilter,
+	lg *zap.Logger) ([]domain.HouseAddressMatch, error) {
+	lg.Info("postgres house repo: search by address", zap.String("query", filter.Query))
+
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		lg.Warn("postgres house repo: search by address error", zap.Error(err))
+		return nil, fmt.Errorf("postgres house repo: search by address error: %v", err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	// <% compares against this setting and is the operator the trigram index can serve
+	query := `select set_config('pg_trgm.word_similarity_threshold', $1, true)`
+	_, err = tx.Exec(ctx, query, strconv.FormatFloat(filter.Threshold, 'f', -1, 64))
+	if err != nil {
+		lg.Warn("postgres house repo: search by address error", zap.Error(err))
+		return nil, fmt.Errorf("postgres house repo: search by address error: %v", err.Error())
+	}
+
+	query = `select ` + houseColumns + `, word_similarity($1, normalized_address) as similarity
+		from houses
+		where $1 <% normalized_address
+		order by similarity desc, house_id
+		limit $2`
+	rows, err := tx.Query(ctx, query, filter.Query, filter.Limit)
+	if err != nil {
+		lg.Warn("postgres house repo: search by address error", zap.Error(err))
+		return nil, fmt.Errorf("postgres house repo: search by address error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var houses []domain.HouseAddressMatch
+	for rows.Next() {
+		var match domain.HouseAddressMatch
+		err = scanHouse(rows, &match.House, &match.Similarity)
+		if err != nil {
+			lg.Warn("postgres house repo: search by address error: scan house error", zap.Error(err))
+			continue
+		}
+		houses = append(houses, match)
+	}
+
+	return houses, rows.Err()
+}
+
+// GetUnnormalizedBatch pages through houses stored before address normalization existed.
+func (p *PostgresHouseRepo) GetUnnormalizedBatch(ctx context.Context, afterHouseID int, limit int,
+	lg *zap.Logger) ([]domain.House, error) {
+	lg.Info("postgres house repo: get unnormalized batch", zap.Int("house_id", afterHouseID))
+
+	query := `select ` + houseColumns + `
+		from houses
+		where normalized_address is null and house_id > $1
+		order by house_id
+		limit $2`
+	rows, err := p.retryAdapter.Query(ctx, query, afterHouseID, limit)
+	if err != nil {
+		lg.Warn("postgres house repo: get unnormalized batch error", zap.Error(err))
+		return nil, fmt.Errorf("postgres house repo: get unnormalized batch error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var houses []domain.House
+	for rows.Next() {
+		var house domain.House
+		err = scanHouse(rows, &house)
+		if err != nil {
+			lg.Warn("postgres house repo: get unnormalized batch error: scan house error", zap.Error(err))
+			return nil, fmt.Errorf("postgres house repo: get unnormalized batch error: %v", err.Error())
+		}
+		houses = append(houses, house)
+	}
+
+	return houses, rows.Err()
+}
+
+// SetNormalizedAddress stores the normalized form unless the address was edited meanwhile
+// and returns the number of rows changed. The version is left as is: the column is derived data
+// and must not fail a client's If-Match.
+func (p *PostgresHouseRepo) SetNormalizedAddress(ctx context.Context, house *domain.House, lg *zap.Logger) (int64, error) {
+	lg.Info("postgres house repo: set normalized address", zap.Int("house_id", house.HouseID))
+
+	query := `update houses set normalized_address=$1 where house_id=$2 and address=$3`
+	tag, err := p.db.Exec(ctx, query, house.NormalizedAddress, house.HouseID, house.Address)
+	if err != nil {
+		lg.Warn("postgres house repo: set normalized address error", zap.Error(err))
+		return 0, fmt.Errorf("postgres house repo: set normalized address error: %v", err.Error())
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetPriceBuckets reads the approved flats counters the flats trigger keeps per rooms and price, cheapest first.
+func (p *PostgresHouseRepo) GetPriceBuckets(ctx context.Context, id int, lg *zap.Logger) ([]domain.HousePriceBucket, error) {
+	lg.Info("postgres house repo: get price buckets", zap.Int("house_id", id))
+
+	query := `select rooms, price, flats
+		from house_price_counts
+		where house_id=$1 and flats > 0
+		order by price, rooms`
+	rows, err := p.retryAdapter.Query(ctx, query, id)
+	if err != nil {
+		lg.Warn("postgres house repo: get price buckets error", zap.Error(err))
+		return nil, fmt.Errorf("postgres house repo: get price buckets error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	var buckets []domain.HousePriceBucket
+	for rows.Next() {
+		var bucket domain.HousePriceBucket
+		err = rows.Scan(&bucket.Rooms, &bucket.Price, &bucket.Flats)
+		if err != nil {
+			lg.Warn("postgres house repo: get price buckets error", zap.Error(err))
+			return nil, fmt.Errorf("postgres house repo: get price buckets error: %v", err.Error())
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, rows.Err()
+}
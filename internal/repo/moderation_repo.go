@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"avito-test-task/internal/domain"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type PostgresModerationRepo struct {
+	db           *pgxpool.Pool
+	retryAdapter IPostgresRetryAdapter
+}
+
+func NewPostgresModerationRepo(db *pgxpool.Pool, retryAdapter IPostgresRetryAdapter) *PostgresModerationRepo {
+	return &PostgresModerationRepo{
+		db:           db,
+		retryAdapter: retryAdapter,
+	}
+}
+
+func (p *PostgresModerationRepo) Claim(ctx context.Context, moderatorID uuid.UUID, expiresAt time.Time, lg *zap.Logger) (domain.Flat, error) {
+	lg.Info("postgres moderation repo: claim", zap.String("moderator_id", moderatorID.String()))
+
+	var flat domain.Flat
+
+	// skip locked lets concurrent moderators take different flats instead of waiting on the same one
+	query := `with next as (
+			select flat_id, house_id from flats
+			where status = 'created'
+			order by status_date, house_id, flat_id
+			limit 1
+			for update skip locked
+		), changed as (
+			update flats set status = 'on moderation', moderator_id = $1, moderation_expires_at = $2,
+				status_date = now(), version = version + 1
+			where (flat_id, house_id) = (select flat_id, house_id from next)
+			returning ` + flatColumns + `
+		), history as (
+			insert into flat_status_history(flat_id, house_id, from_status, to_status, actor_id)
+			select flat_id, house_id, 'created', status, $1 from changed
+		), touched as (
+			update houses set update_flat_date = clock_timestamp()
+			where house_id in (select house_id from changed)
+		)
+		select ` + flatColumns + ` from changed`
+	rows, err := p.retryAdapter.Query(ctx, query, moderatorID, expiresAt)
+	if err != nil {
+		lg.Warn("postgres moderation repo: claim error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres moderation repo: claim error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			lg.Warn("postgres moderation repo: claim error", zap.Error(err))
+			return domain.Flat{}, fmt.Errorf("postgres moderation repo: claim error: %v", err.Error())
+		}
+		lg.Info("postgres moderation repo: claim: queue is empty")
+		return domain.Flat{}, fmt.Errorf("postgres moderation repo: claim error: %w", domain.ErrModeration_EmptyQueue)
+	}
+
+	err = scanFlat(rows, &flat)
+	if err != nil {
+		lg.Warn("postgres moderation repo: claim error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres moderation repo: claim error: %v", err.Error())
+	}
+
+	return flat, nil
+}
+
+func (p *PostgresModerationRepo) GetQueue(ctx context.Context, limit int, lg *zap.Logger) (domain.ModerationQueue, error) {
+	lg.Info("postgres moderation repo: get queue", zap.Int("limit", limit))
+
+	var queue domain.ModerationQueue
+
+	query := `select count(*) filter (where status = 'created'),
+			count(*) filter (where status = 'on moderation'),
+			coalesce(extract(epoch from now() - min(status_date) filter (where status = 'created')), 0)::bigint,
+			coalesce(extract(epoch from avg(now() - status_date) filter (where status = 'created')), 0)::bigint
+		from flats
+		where status in ('created', 'on moderation')`
+	rows, err := p.retryAdapter.Query(ctx, query)
+	if err != nil {
+		lg.Warn("postgres moderation repo: get queue error", zap.Error(err))
+		return domain.ModerationQueue{}, fmt.Errorf("postgres moderation repo: get queue error: %v", err.Error())
+	}
+	if rows.Next() {
+		err = rows.Scan(&queue.Depth, &queue.Claimed, &queue.OldestAgeSec, &queue.AverageAgeSec)
+	}
+	rows.Close()
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		lg.Warn("postgres moderation repo: get queue error", zap.Error(err))
+		return domain.ModerationQueue{}, fmt.Errorf("postgres moderation repo: get queue error: %v", err.Error())
+	}
+
+	query = `select flat_id, house_id, extract(epoch from now() - status_date)::bigint, coalesce(duplicate_of, 0)
+		from flats
+		where status = 'created'
+		order by status_date, house_id, flat_id
+		limit $1`
+	rows, err = p.retryAdapter.Query(ctx, query, limit)
+	if err != nil {
+		lg.Warn("postgres moderation repo: get queue error", zap.Error(err))
+		return domain.ModerationQueue{}, fmt.Errorf("postgres moderation repo: get queue error: %v", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item domain.ModerationQueueItem
+		err = rows.Scan(&item.FlatID, &item.HouseID, &item.WaitingSec, &item.DuplicateOf)
+		if err != nil {
+			lg.Warn("postgres moderation repo: get queue error: scan item error", zap.Error(err))
+			continue
+		}
+		queue.Items = append(queue.Items, item)
+	}
+
+	return queue, rows.Err()
+}
+
+func (p *PostgresModerationRepo) Renew(ctx context.Context, flatID int, houseID int, moderatorID uuid.UUID,
+	expiresAt time.Time, lg *zap.Logger) (domain.Flat, error) {
+	lg.Info("postgres moderation repo: renew", zap.Int("flat_id", flatID), zap.Int("house_id", houseID))
+
+	var flat domain.Flat
+
+	// the lease is not part of the flat: the moderator's ETag stays valid and
+	// the house update date does not move
+	query := `update flats set moderation_expires_at = $1
+		where flat_id = $2 and house_id = $3 and status = 'on moderation' and moderator_id = $4
+		returning ` + flatColumns
+	rows, err := p.retryAdapter.Query(ctx, query, expiresAt, flatID, houseID, moderatorID)
+	if err != nil {
+		lg.Warn("postgres moderation repo: renew error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres moderation repo: renew error: %v", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			lg.Warn("postgres moderation repo: renew error", zap.Error(err))
+			return domain.Flat{}, fmt.Errorf("postgres moderation repo: renew error: %v", err.Error())
+		}
+		lg.Warn("postgres moderation repo: renew error: flat is not claimed by moderator")
+		return domain.Flat{}, fmt.Errorf("postgres moderation repo: renew error: %w", domain.ErrModeration_NotClaimed)
+	}
+
+	err = scanFlat(rows, &flat)
+	if err != nil {
+		lg.Warn("postgres moderation repo: renew error", zap.Error(err))
+		return domain.Flat{}, fmt.Errorf("postgres moderation repo: renew error: %v", err.Error())
+	}
+
+	return flat, nil
+}
+
+func (p *PostgresModerationRepo) ReleaseExpired(ctx context.Context, now time.Time, lg *zap.Logger) (int64, error) {
+	lg.Info("postgres moderation repo: release expired")
+
+	// the history row has no actor: the claim is released by the service itself
+	query := `with released as (
+			update flats set status = 'created', moderator_id = null, moderation_expires_at = null,
+				status_date = now(), version = version + 1
+			where status = 'on moderation' and moderation_expires_at < $1
+			returning flat_id, house_id
+		), touched as (
+			update houses set update_flat_date = clock_timestamp()
+			where house_id in (select house_id from released)
+		)
+		insert into flat_status_history(flat_id, house_id, from_status, to_status, comment)
+		select flat_id, house_id, 'on moderation', 'created', 'moderation lease expired' from released`
+	tag, err := p.db.Exec(ctx, query, now)
+	if err != nil {
+		lg.Warn("postgres moderation repo: release expired error", zap.Error(err))
+		return 0, fmt.Errorf("postgres moderation repo: release expired error: %v", err.Error())
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -4,6 +4,7 @@ import (
 	"avito-test-task/internal/domain"
 	"context"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -23,7 +24,7 @@ func NewPostgresNotifyRepo(pg *pgxpool.Pool, retryAdapter IPostgresRetryAdapter)
 func (p *PostgresNotifyRepo) GetNoSendNotifies(ctx context.Context, lg *zap.Logger) ([]domain.Notify, error) {
 	lg.Info("postgres notify repo: get no send notifies")
 
-	query := `select * from new_flats_outbox where status=$1`
+	query := `select id, flat_id, house_id, user_id, mail, status from new_flats_outbox where status=$1`
 	rows, err := p.retryAdapter.Query(ctx, query, domain.NoSendedNotifyStatus)
 	defer rows.Close()
 	if err != nil {
@@ -34,14 +35,20 @@ func (p *PostgresNotifyRepo) GetNoSendNotifies(ctx context.Context, lg *zap.Logg
 	var (
 		notifies []domain.Notify
 		notify   domain.Notify
+		userID   *uuid.UUID
 	)
 
 	for rows.Next() {
-		err = rows.Scan(&notify.ID, &notify.FlatID, &notify.HouseID, &notify.UserMail, &notify.Status)
+		err = rows.Scan(&notify.ID, &notify.FlatID, &notify.HouseID, &userID, &notify.UserMail, &notify.Status)
 		if err != nil {
 			lg.Warn("postgres notify repo: get no send notify error: scan notify error")
 			continue
 		}
+		// rows queued before recipients were stored by id have no user
+		notify.UserID = uuid.Nil
+		if userID != nil {
+			notify.UserID = *userID
+		}
 		notifies = append(notifies, notify)
 	}
 
@@ -51,8 +58,8 @@ func (p *PostgresNotifyRepo) GetNoSendNotifies(ctx context.Context, lg *zap.Logg
 func (p *PostgresNotifyRepo) SendNotifyByID(ctx context.Context, id int, lg *zap.Logger) error {
 	lg.Info("postgres notify repo: send notify by id")
 
-	query := `update new_flats_outbox set status=$1`
-	_, err := p.retryAdapter.Exec(ctx, query, domain.SendedNotifyStatus)
+	query := `update new_flats_outbox set status=$1 where id=$2`
+	_, err := p.retryAdapter.Exec(ctx, query, domain.SendedNotifyStatus, id)
 	if err != nil {
 		lg.Warn("postgres notify repo: send notify by id error", zap.Error(err))
 		return fmt.Errorf("postgres notify repo: send notify by id error: %v", err.Error())
@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// likeEscaper makes user input match literally inside a like pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type conditionBuilder struct {
+	conditions []string
+	args       []any
+}
+
+func (b *conditionBuilder) arg(value any) int {
+	b.args = append(b.args, value)
+	return len(b.args)
+}
+
+func (b *conditionBuilder) add(condition string, values ...any) {
+	placeholders := make([]any, len(values))
+	for i, value := range values {
+		placeholders[i] = b.arg(value)
+	}
+	b.conditions = append(b.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+func (b *conditionBuilder) where() string {
+	if len(b.conditions) == 0 {
+		return "true"
+	}
+	return strings.Join(b.conditions, " and ")
+}
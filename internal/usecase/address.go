@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// addressAbbreviations expands the usual shortenings of Russian addresses, keyed by the lower-case form without the dot.
+var addressAbbreviations = map[string]string{
+	"ул":    "улица",
+	"пр":    "проспект",
+	"пр-т":  "проспект",
+	"пр-кт": "проспект",
+	"просп": "проспект",
+	"пр-д":  "проезд",
+	"пер":   "переулок",
+	"б-р":   "бульвар",
+	"бул":   "бульвар",
+	"ш":     "шоссе",
+	"наб":   "набережная",
+	"пл":    "площадь",
+	"туп":   "тупик",
+	"мкр":   "микрорайон",
+	"мкрн":  "микрорайон",
+	"р-н":   "район",
+	"обл":   "область",
+	"г":     "город",
+	"д":     "дом",
+	"к":     "корпус",
+	"корп":  "корпус",
+	"стр":   "строение",
+	"кв":    "квартира",
+	"лит":   "литера",
+}
+
+func normalizeAddressWord(word string) string {
+	word = strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+	if full, ok := addressAbbreviations[word]; ok {
+		return full
+	}
+	return word
+}
+
+// addressToken is a word of an address or the separator text between words.
+type addressToken struct {
+	text string
+	word bool
+}
+
+func isAddressWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '/'
+}
+
+// splitAddress cuts the address into words and separators. Words keep inner hyphens and slashes,
+// as in "пр-т" and "7/1"; hyphens and slashes at the edges of a word go to the separators.
+func splitAddress(address string) []addressToken {
+	var tokens []addressToken
+	separator := func(text string) {
+		if text != "" {
+			tokens = append(tokens, addressToken{text: text})
+		}
+	}
+
+	for address != "" {
+		end := strings.IndexFunc(address, func(r rune) bool { return !isAddressWordRune(r) })
+		if end == 0 {
+			end = strings.IndexFunc(address, isAddressWordRune)
+			if end < 0 {
+				end = len(address)
+			}
+			separator(address[:end])
+			address = address[end:]
+			continue
+		}
+		if end < 0 {
+			end = len(address)
+		}
+
+		run := address[:end]
+		word := strings.Trim(run, "-/")
+		if word == "" {
+			separator(run)
+		} else {
+			start := strings.Index(run, word)
+			separator(run[:start])
+			tokens = append(tokens, addressToken{text: word, word: true})
+			separator(run[start+len(word):])
+		}
+		address = address[end:]
+	}
+
+	return tokens
+}
+
+// NormalizeAddress lower-cases the address, drops punctuation and expands abbreviations,
+// so "ул. Тверская, д.7" and "улица тверская дом 7" are stored the same way.
+func NormalizeAddress(address string) string {
+	var words []string
+	for _, token := range splitAddress(address) {
+		if token.word {
+			words = append(words, normalizeAddressWord(token.text))
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// highlightAddress wraps the words of the address that match a query word in <mark> and escapes the rest.
+// Words are cut the same way as in NormalizeAddress, so every word the search matched can be marked.
+func highlightAddress(address string, queryWords []string) string {
+	var result strings.Builder
+	for _, token := range splitAddress(address) {
+		text := html.EscapeString(token.text)
+		if token.word && matchesAddressWord(normalizeAddressWord(token.text), queryWords) {
+			text = "<mark>" + text + "</mark>"
+		}
+		result.WriteString(text)
+	}
+
+	return result.String()
+}
+
+// matchesAddressWord accepts equal words, typed prefixes and small typos in longer words.
+func matchesAddressWord(word string, queryWords []string) bool {
+	for _, queryWord := range queryWords {
+		if word == queryWord {
+			return true
+		}
+		queryRunes, wordRunes := []rune(queryWord), []rune(word)
+		if len(queryRunes) >= 3 && strings.HasPrefix(word, queryWord) {
+			return true
+		}
+		allowed := 0
+		switch {
+		case len(queryRunes) >= 8:
+			allowed = 2
+		case len(queryRunes) >= 4:
+			allowed = 1
+		}
+		if allowed > 0 && editDistance(queryRunes, wordRunes) <= allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func editDistance(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// BackfillAddresses stores the normalized address of houses created before normalization existed,
+// batchSize houses at a time. It returns the number of houses updated; houses whose address was edited
+// meanwhile already got the normalized form from the edit and are not counted.
+func (u *HouseUsecase) BackfillAddresses(ctx context.Context, batchSize int, lg *zap.Logger) (int, error) {
+	lg.Info("house usecase: backfill addresses", zap.Int("batch_size", batchSize))
+
+	var afterHouseID, updated int
+	for {
+		houses, err := u.houseRepo.GetUnnormalizedBatch(ctx, afterHouseID, batchSize, lg)
+		if err != nil {
+			lg.Warn("house usecase: backfill addresses error", zap.Error(err))
+			return updated, fmt.Errorf("house usecase: backfill addresses error: %v", err.Error())
+		}
+		if len(houses) == 0 {
+			return updated, nil
+		}
+
+		for i := range houses {
+			houses[i].NormalizedAddress = NormalizeAddress(houses[i].Address)
+			rows, err := u.houseRepo.SetNormalizedAddress(ctx, &houses[i], lg)
+			if err != nil {
+				lg.Warn("house usecase: backfill addresses error", zap.Error(err))
+				return updated, fmt.Errorf("house usecase: backfill addresses error: %v", err.Error())
+			}
+			updated += int(rows)
+		}
+
+		afterHouseID = houses[len(houses)-1].HouseID
+		lg.Info("house usecase: backfill addresses: batch done", zap.Int("house_id", afterHouseID),
+			zap.Int("updated", updated))
+	}
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"avito-test-task/internal/domain"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// AutoModerator checks new flats against the configured rules in file order; the first rule that fires wins.
+type AutoModerator struct {
+	rules []domain.AutoModerationRule
+}
+
+func NewAutoModerator(rules []domain.AutoModerationRule, declineReasons []domain.DeclineReason) (*AutoModerator, error) {
+	for _, rule := range rules {
+		switch rule.Kind {
+		case domain.PriceOutlierRule, domain.BannedWordsRule, domain.RoomsPriceRule, domain.TrustedOwnerRule:
+		default:
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, domain.ErrAutoModeration_BadKind)
+		}
+
+		switch rule.Outcome {
+		case domain.AutoApproveOutcome, domain.AutoFlagOutcome:
+		case domain.AutoDeclineOutcome:
+			if !hasDeclineReason(declineReasons, rule.ReasonCode) {
+				return nil, fmt.Errorf("rule %q: %w", rule.Name, domain.ErrAutoModeration_BadReason)
+			}
+		default:
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, domain.ErrAutoModeration_BadOutcome)
+		}
+	}
+
+	return &AutoModerator{rules: rules}, nil
+}
+
+func hasDeclineReason(declineReasons []domain.DeclineReason, code string) bool {
+	for _, reason := range declineReasons {
+		if reason.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsPriceStats reports whether the house price median has to be loaded before Evaluate.
+func (m *AutoModerator) NeedsPriceStats() bool {
+	for _, rule := range m.rules {
+		if rule.Kind == domain.PriceOutlierRule {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns nil when no rule fired and the flat goes to the moderation queue as usual.
+func (m *AutoModerator) Evaluate(flat *domain.Flat, stats domain.HousePriceStats) *domain.AutoModerationVerdict {
+	for _, rule := range m.rules {
+		explanation, fired := evaluateRule(&rule, flat, stats)
+		if !fired {
+			continue
+		}
+
+		return &domain.AutoModerationVerdict{
+			Rule:       rule.Name,
+			Outcome:    rule.Outcome,
+			ReasonCode: rule.ReasonCode,
+			Comment:    fmt.Sprintf("auto-moderation rule %s: %s", rule.Name, explanation),
+		}
+	}
+	return nil
+}
+
+func evaluateRule(rule *domain.AutoModerationRule, flat *domain.Flat, stats domain.HousePriceStats) (string, bool) {
+	switch rule.Kind {
+	case domain.PriceOutlierRule:
+		// a median over a handful of flats says nothing about the market
+		if stats.Flats < rule.MinFlats || stats.MedianPrice <= 0 {
+			return "", false
+		}
+		deviation := math.Abs(float64(flat.Price)-stats.MedianPrice) / stats.MedianPrice
+		if deviation > rule.MaxDeviation {
+			return fmt.Sprintf("price %d deviates from house median %.0f by %.0f%%",
+				flat.Price, stats.MedianPrice, deviation*100), true
+		}
+
+	case domain.BannedWordsRule:
+		description := strings.ToLower(flat.Description)
+		for _, word := range rule.Words {
+			if word != "" && strings.Contains(description, strings.ToLower(word)) {
+				return fmt.Sprintf("description contains %q", word), true
+			}
+		}
+
+	case domain.RoomsPriceRule:
+		if rule.MaxRooms > 0 && flat.Rooms > rule.MaxRooms {
+			return fmt.Sprintf("%d rooms is more than %d", flat.Rooms, rule.MaxRooms), true
+		}
+		pricePerRoom := flat.Price / flat.Rooms
+		if rule.MinPricePerRoom > 0 && pricePerRoom < rule.MinPricePerRoom {
+			return fmt.Sprintf("price per room %d is less than %d", pricePerRoom, rule.MinPricePerRoom), true
+		}
+		if rule.MaxPricePerRoom > 0 && pricePerRoom > rule.MaxPricePerRoom {
+			return fmt.Sprintf("price per room %d is more than %d", pricePerRoom, rule.MaxPricePerRoom), true
+		}
+
+	case domain.TrustedOwnerRule:
+		for _, owner := range rule.Owners {
+			if owner == flat.UserID {
+				return "owner is trusted", true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"avito-test-task/internal/domain"
+	"avito-test-task/pkg"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type DeveloperUsecase struct {
+	developerRepo domain.DeveloperRepo
+	houseRepo     domain.HouseRepo
+}
+
+func NewDeveloperUsecase(developerRepo domain.DeveloperRepo, houseRepo domain.HouseRepo) *DeveloperUsecase {
+	return &DeveloperUsecase{
+		developerRepo: developerRepo,
+		houseRepo:     houseRepo,
+	}
+}
+
+func toDeveloperResponse(developer *domain.Developer) domain.DeveloperResponse {
+	return domain.DeveloperResponse{
+		ID:        developer.DeveloperID,
+		Name:      developer.Name,
+		CreatedAt: developer.CreateDeveloperDate.Format(time.DateTime),
+	}
+}
+
+// checkDeveloperName trims the name and rejects names without a single letter or digit.
+func checkDeveloperName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > domain.MaxDeveloperNameLength ||
+		strings.IndexFunc(name, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		return "", domain.ErrDeveloper_BadName
+	}
+	return name, nil
+}
+
+func (u *DeveloperUsecase) Create(ctx context.Context, req *domain.CreateDeveloperRequest, lg *zap.Logger) (domain.DeveloperResponse, error) {
+	lg.Info("developer usecase: create")
+
+	if req == nil {
+		lg.Warn("developer usecase: create error: bad request = nil")
+		return domain.DeveloperResponse{},
+			fmt.Errorf("developer usecase: create error: %w", domain.ErrDeveloper_BadRequest)
+	}
+
+	name, err := checkDeveloperName(req.Name)
+	if err != nil {
+		lg.Warn("developer usecase: create error: bad name", zap.String("name", req.Name))
+		return domain.DeveloperResponse{}, fmt.Errorf("developer usecase: create error: %w", err)
+	}
+
+	developer, err := u.developerRepo.Create(ctx, &domain.Developer{Name: name}, lg)
+	if err != nil {
+		lg.Warn("developer usecase: create error", zap.Error(err))
+		return domain.DeveloperResponse{}, fmt.Errorf("developer usecase: create error: %w", err)
+	}
+
+	return toDeveloperResponse(&developer), nil
+}
+
+func (u *DeveloperUsecase) GetByID(ctx context.Context, id int, lg *zap.Logger) (domain.DeveloperResponse, error) {
+	lg.Info("developer usecase: get by id")
+
+	if id < 1 {
+		lg.Warn("developer usecase: get by id error: bad id", zap.Int("developer_id", id))
+		return domain.DeveloperResponse{},
+			fmt.Errorf("developer usecase: get by id error: %w", domain.ErrDeveloper_BadID)
+	}
+
+	developer, err := u.developerRepo.GetByID(ctx, id, lg)
+	if err != nil {
+		lg.Warn("developer usecase: get by id error", zap.Error(err))
+		return domain.DeveloperResponse{}, fmt.Errorf("developer usecase: get by id error: %w", err)
+	}
+
+	return toDeveloperResponse(&developer), nil
+}
+
+func (u *DeveloperUsecase) GetAll(ctx context.Context, req *domain.DevelopersRequest, lg *zap.Logger) (domain.DevelopersResponse, error) {
+	lg.Info("developer usecase: get all")
+
+	if req == nil {
+		lg.Warn("developer usecase: get all error: bad request = nil")
+		return domain.DevelopersResponse{},
+			fmt.Errorf("developer usecase: get all error: %w", domain.ErrDeveloper_BadRequest)
+	}
+
+	limit, err := pageLimit(req.Limit)
+	if err != nil {
+		lg.Warn("developer usecase: get all error: bad limit", zap.Int("limit", req.Limit))
+		return domain.DevelopersResponse{},
+			fmt.Errorf("developer usecase: get all error: %w", domain.ErrDeveloper_BadLimit)
+	}
+
+	filter := domain.DevelopersFilter{Limit: limit + 1}
+	if req.Cursor != "" {
+		var cursor domain.DevelopersCursor
+		err = pkg.DecodeCursor(req.Cursor, &cursor)
+		if err != nil {
+			lg.Warn("developer usecase: get all error: bad cursor", zap.String("cursor", req.Cursor))
+			return domain.DevelopersResponse{},
+				fmt.Errorf("developer usecase: get all error: %w", domain.ErrDeveloper_BadCursor)
+		}
+		filter.After = &cursor
+	}
+
+	developers, err := u.developerRepo.GetAll(ctx, &filter, lg)
+	if err != nil {
+		lg.Warn("developer usecase: get all error", zap.Error(err))
+		return domain.DevelopersResponse{}, fmt.Errorf("developer usecase: get all error: %v", err.Error())
+	}
+
+	var response domain.DevelopersResponse
+	if len(developers) > limit {
+		developers = developers[:limit]
+		last := developers[limit-1]
+		response.NextCursor, err = pkg.EncodeCursor(domain.DevelopersCursor{
+			Name:        last.Name,
+			DeveloperID: last.DeveloperID,
+		})
+		if err != nil {
+			lg.Warn("developer usecase: get all error: encode cursor", zap.Error(err))
+			return domain.DevelopersResponse{}, fmt.Errorf("developer usecase: get all error: %v", err.Error())
+		}
+	}
+
+	response.Developers = make([]domain.DeveloperResponse, 0, len(developers))
+	for i := range developers {
+		response.Developers = append(response.Developers, toDeveloperResponse(&developers[i]))
+	}
+
+	return response, nil
+}
+
+func (u *DeveloperUsecase) Update(ctx context.Context, req *domain.UpdateDeveloperRequest, lg *zap.Logger) (domain.DeveloperResponse, error) {
+	lg.Info("developer usecase: update")
+
+	if req == nil {
+		lg.Warn("developer usecase: update error: bad request = nil")
+		return domain.DeveloperResponse{},
+			fmt.Errorf("developer usecase: update error: %w", domain.ErrDeveloper_BadRequest)
+	}
+
+	if req.ID < 1 {
+		lg.Warn("developer usecase: update error: bad id", zap.Int("developer_id", req.ID))
+		return domain.DeveloperResponse{},
+			fmt.Errorf("developer usecase: update error: %w", domain.ErrDeveloper_BadID)
+	}
+
+	name, err := checkDeveloperName(req.Name)
+	if err != nil {
+		lg.Warn("developer usecase: update error: bad name", zap.String("name", req.Name))
+		return domain.DeveloperResponse{}, fmt.Errorf("developer usecase: update error: %w", err)
+	}
+
+	developer, err := u.developerRepo.Update(ctx, &domain.Developer{DeveloperID: req.ID, Name: name}, lg)
+	if err != nil {
+		lg.Warn("developer usecase: update error", zap.Error(err))
+		return domain.DeveloperResponse{}, fmt.Errorf("developer usecase: update error: %w", err)
+	}
+
+	return toDeveloperResponse(&developer), nil
+}
+
+func (u *DeveloperUsecase) Delete(ctx context.Context, id int, lg *zap.Logger) error {
+	lg.Info("developer usecase: delete")
+
+	if id < 1 {
+		lg.Warn("developer usecase: delete error: bad id", zap.Int("developer_id", id))
+		return fmt.Errorf("developer usecase: delete error: %w", domain.ErrDeveloper_BadID)
+	}
+
+	err := u.developerRepo.DeleteByID(ctx, id, lg)
+	if err != nil {
+		lg.Warn("developer usecase: delete error", zap.Error(err))
+		return fmt.Errorf("developer usecase: delete error: %w", err)
+	}
+
+	return nil
+}
+
+func (u *DeveloperUsecase) GetHouses(ctx context.Context, req *domain.DeveloperHousesRequest, lg *zap.Logger) (domain.HouseSearchResponse, error) {
+	lg.Info("developer usecase: get houses")
+
+	if req == nil {
+		lg.Warn("developer usecase: get houses error: bad request = nil")
+		return domain.HouseSearchResponse{},
+			fmt.Errorf("developer usecase: get houses error: %w", domain.ErrDeveloper_BadRequest)
+	}
+
+	if req.ID < 1 {
+		lg.Warn("developer usecase: get houses error: bad id", zap.Int("developer_id", req.ID))
+		return domain.HouseSearchResponse{},
+			fmt.Errorf("developer usecase: get houses error: %w", domain.ErrDeveloper_BadID)
+	}
+
+	limit, err := pageLimit(req.Limit)
+	if err != nil {
+		lg.Warn("developer usecase: get houses error: bad limit", zap.Int("limit", req.Limit))
+		return domain.HouseSearchResponse{},
+			fmt.Errorf("developer usecase: get houses error: %w", domain.ErrHouse_BadLimit)
+	}
+
+	_, err = u.developerRepo.GetByID(ctx, req.ID, lg)
+	if err != nil {
+		lg.Warn("developer usecase: get houses error", zap.Error(err))
+		return domain.HouseSearchResponse{}, fmt.Errorf("developer usecase: get houses error: %w", err)
+	}
+
+	filter := domain.HouseSearchFilter{DeveloperID: req.ID, Limit: limit}
+	response, err := searchHousePage(ctx, u.houseRepo, &filter, req.Cursor, lg)
+	if err != nil {
+		lg.Warn("developer usecase: get houses error", zap.Error(err))
+		return domain.HouseSearchResponse{}, fmt.Errorf("developer usecase: get houses error: %w", err)
+	}
+
+	return response, nil
+}
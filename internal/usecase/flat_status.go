@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"avito-test-task/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Actors of flat status transitions. The owner is the user who created the flat,
+// regardless of the role in the token.
+const (
+	OwnerActor     = "owner"
+	ModeratorActor = "moderator"
+)
+
+type flatTransition struct {
+	from string
+	to   string
+}
+
+// flatTransitions lists every legal status change and the actors allowed to make it.
+var flatTransitions = map[flatTransition][]string{
+	// moderation
+	{domain.CreatedStatus, domain.ModeratingStatus}:  {ModeratorActor},
+	{domain.ModeratingStatus, domain.ApprovedStatus}: {ModeratorActor},
+	{domain.ModeratingStatus, domain.DeclinedStatus}: {ModeratorActor},
+	{domain.ModeratingStatus, domain.CreatedStatus}:  {ModeratorActor},
+
+	// re-submission after owner's edit
+	{domain.CreatedStatus, domain.CreatedStatus}:  {OwnerActor},
+	{domain.ApprovedStatus, domain.CreatedStatus}: {OwnerActor},
+	{domain.DeclinedStatus, domain.CreatedStatus}: {OwnerActor},
+
+	// archiving; restoring returns the status saved at archiving time,
+	// so it isn't listed here
+	{domain.CreatedStatus, domain.ArchivedStatus}:    {OwnerActor, ModeratorActor},
+	{domain.ModeratingStatus, domain.ArchivedStatus}: {OwnerActor, ModeratorActor},
+	{domain.ApprovedStatus, domain.ArchivedStatus}:   {OwnerActor, ModeratorActor},
+	{domain.DeclinedStatus, domain.ArchivedStatus}:   {OwnerActor, ModeratorActor},
+}
+
+// CheckFlatTransition reports whether actor may move a flat from one status to another.
+func CheckFlatTransition(from string, to string, actor string) error {
+	for _, allowed := range flatTransitions[flatTransition{from: from, to: to}] {
+		if allowed == actor {
+			return nil
+		}
+	}
+	return &domain.FlatTransitionError{From: from, To: to, Actor: actor}
+}
+
+// CheckModeratorTransition additionally checks that a flat on moderation is released
+// only by the moderator who took it.
+func CheckModeratorTransition(flat *domain.Flat, to string, moderatorID uuid.UUID) error {
+	err := CheckFlatTransition(flat.Status, to, ModeratorActor)
+	if err != nil {
+		return err
+	}
+	if flat.Status == domain.ModeratingStatus && flat.ModeratorID != moderatorID {
+		return domain.ErrFlat_OnModeration
+	}
+	return nil
+}
+
+func flatActor(flat *domain.Flat, userID uuid.UUID) string {
+	if flat.UserID == userID {
+		return OwnerActor
+	}
+	return ModeratorActor
+}
+
+// moderatorAfter returns the moderator holding the flat once it gets the new status.
+func moderatorAfter(to string, moderatorID uuid.UUID) uuid.UUID {
+	if to == domain.ModeratingStatus {
+		return moderatorID
+	}
+	return uuid.Nil
+}
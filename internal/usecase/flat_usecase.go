@@ -2,18 +2,62 @@ package usecase
 
 import (
 	"avito-test-task/internal/domain"
+	"avito-test-task/pkg"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"math"
+	"time"
+	"unicode/utf8"
 )
 
 type FlatUsecase struct {
-	flatRepo domain.FlatRepo
+	flatRepo        domain.FlatRepo
+	moderationLease time.Duration
+	declineReasons  []domain.DeclineReason
+	autoModerator   *AutoModerator
 }
 
-func NewFlatUsecase(flatRepo domain.FlatRepo) *FlatUsecase {
-	return &FlatUsecase{flatRepo: flatRepo}
+// NewFlatUsecase accepts a nil autoModerator, then every new flat waits for a moderator.
+func NewFlatUsecase(flatRepo domain.FlatRepo, moderationLease time.Duration,
+	declineReasons []domain.DeclineReason, autoModerator *AutoModerator) *FlatUsecase {
+	return &FlatUsecase{
+		flatRepo:        flatRepo,
+		moderationLease: moderationLease,
+		declineReasons:  declineReasons,
+		autoModerator:   autoModerator,
+	}
+}
+
+func (u *FlatUsecase) findDeclineReason(code string) (domain.DeclineReason, bool) {
+	for _, reason := range u.declineReasons {
+		if reason.Code == code {
+			return reason, true
+		}
+	}
+	return domain.DeclineReason{}, false
+}
+
+// checkDecision validates the reason and the comment of a moderator's decision:
+// declines need a reason from the catalog, the comment is allowed only with a decision.
+func (u *FlatUsecase) checkDecision(req *domain.UpdateFlatRequest) error {
+	isDecision := req.Status == domain.ApprovedStatus || req.Status == domain.DeclinedStatus
+
+	if req.Status == domain.DeclinedStatus {
+		if _, ok := u.findDeclineReason(req.ReasonCode); !ok {
+			return domain.ErrFlat_BadReason
+		}
+	} else if req.ReasonCode != "" {
+		return domain.ErrFlat_BadReason
+	}
+
+	if (!isDecision && req.Comment != "") ||
+		utf8.RuneCountInString(req.Comment) > domain.MaxCommentLength {
+		return domain.ErrFlat_BadComment
+	}
+	return nil
 }
 
 func IsCorrectFlatStatus(status string) bool {
@@ -21,6 +65,44 @@ func IsCorrectFlatStatus(status string) bool {
 		status == domain.DeclinedStatus || status == domain.AnyStatus || status == domain.ModeratingStatus
 }
 
+func isCorrectLayout(layout string) bool {
+	return layout == "" || layout == domain.StudioLayout || layout == domain.IsolatedLayout ||
+		layout == domain.AdjacentLayout || layout == domain.FreeLayout
+}
+
+func isCorrectBalcony(balcony string) bool {
+	return balcony == "" || balcony == domain.NoBalcony || balcony == domain.BalconyBalcony ||
+		balcony == domain.LoggiaBalcony
+}
+
+// validateFlatAttributes checks the optional listing attributes, zero values mean "not specified".
+func validateFlatAttributes(flat *domain.Flat) error {
+	if flat.TotalArea < 0 || flat.LivingArea < 0 ||
+		(flat.TotalArea > 0 && flat.LivingArea > flat.TotalArea) {
+		return domain.ErrFlat_BadArea
+	}
+	if flat.Floor < 0 || flat.Floors < 0 || (flat.Floors > 0 && flat.Floor > flat.Floors) {
+		return domain.ErrFlat_BadFloor
+	}
+	if utf8.RuneCountInString(flat.Description) > domain.MaxDescriptionLength {
+		return domain.ErrFlat_BadDescription
+	}
+	if !isCorrectLayout(flat.Layout) {
+		return domain.ErrFlat_BadLayout
+	}
+	if !isCorrectBalcony(flat.Balcony) {
+		return domain.ErrFlat_BadBalcony
+	}
+	return nil
+}
+
+func pricePerMeter(flat *domain.Flat) float64 {
+	if flat.TotalArea <= 0 {
+		return 0
+	}
+	return math.Round(float64(flat.Price)/flat.TotalArea*100) / 100
+}
+
 func (u *FlatUsecase) Create(ctx context.Context, userID uuid.UUID, flatReq *domain.CreateFlatRequest, lg *zap.Logger) (domain.CreateFlatResponse, error) {
 	lg.Info("flat usecase: create")
 
@@ -30,7 +112,8 @@ func (u *FlatUsecase) Create(ctx context.Context, userID uuid.UUID, flatReq *dom
 			fmt.Errorf("flat usecase: create error: %w", domain.ErrFlat_BadRequest)
 	}
 
-	if flatReq.FlatID < 1 {
+	// zero flat id means the number is assigned by the server
+	if flatReq.FlatID < 0 {
 		lg.Warn("flat usecase: create error: bad flat id", zap.Int("flat_id", flatReq.FlatID))
 		return domain.CreateFlatResponse{},
 			fmt.Errorf("flat usecase: create error: %w", domain.ErrFlat_BadID)
@@ -55,29 +138,76 @@ func (u *FlatUsecase) Create(ctx context.Context, userID uuid.UUID, flatReq *dom
 	}
 
 	flat := domain.Flat{
-		ID:      flatReq.FlatID,
-		HouseID: flatReq.HouseID,
-		UserID:  userID,
-		Price:   flatReq.Price,
-		Rooms:   flatReq.Rooms,
-		Status:  domain.CreatedStatus,
+		ID:          flatReq.FlatID,
+		HouseID:     flatReq.HouseID,
+		UserID:      userID,
+		Price:       flatReq.Price,
+		Rooms:       flatReq.Rooms,
+		Status:      domain.CreatedStatus,
+		TotalArea:   flatReq.TotalArea,
+		LivingArea:  flatReq.LivingArea,
+		Floor:       flatReq.Floor,
+		Floors:      flatReq.Floors,
+		Description: flatReq.Description,
+		Layout:      flatReq.Layout,
+		Balcony:     flatReq.Balcony,
 	}
 
-	createdFlat, err := u.flatRepo.Create(ctx, &flat, lg)
+	err := validateFlatAttributes(&flat)
+	if err != nil {
+		lg.Warn("flat usecase: create error: bad attributes", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: create error: %w", err)
+	}
+
+	flat.DuplicateOf, err = u.flatRepo.FindDuplicate(ctx, &flat, lg)
+	if err != nil {
+		lg.Warn("flat usecase: create: find duplicate error", zap.Error(err))
+	}
+
+	verdict := u.autoModerate(ctx, &flat, lg)
+	// a likely duplicate always waits for a moderator, even when a rule would approve it
+	if verdict != nil && verdict.Outcome == domain.AutoApproveOutcome && flat.DuplicateOf != 0 {
+		verdict = nil
+	}
+	if verdict != nil {
+		switch verdict.Outcome {
+		case domain.AutoApproveOutcome:
+			flat.Status = domain.ApprovedStatus
+		case domain.AutoDeclineOutcome:
+			flat.Status = domain.DeclinedStatus
+		}
+	}
+
+	createdFlat, err := u.flatRepo.Create(ctx, &flat, verdict, lg)
 	if err != nil {
 		lg.Warn("flat usecase repo: create error", zap.Error(err))
-		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: create error: %v", err.Error())
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: create error: %w", err)
 	}
 
-	createdFlatResponse := domain.CreateFlatResponse{
-		ID:      createdFlat.ID,
-		HouseID: createdFlat.HouseID,
-		Price:   createdFlat.Price,
-		Rooms:   createdFlat.Rooms,
-		Status:  createdFlat.Status,
+	return toCreateFlatResponse(&createdFlat), nil
+}
+
+// autoModerate never fails the creation: without price stats the price rules just don't fire.
+func (u *FlatUsecase) autoModerate(ctx context.Context, flat *domain.Flat, lg *zap.Logger) *domain.AutoModerationVerdict {
+	if u.autoModerator == nil {
+		return nil
+	}
+
+	var stats domain.HousePriceStats
+	if u.autoModerator.NeedsPriceStats() {
+		var err error
+		stats, err = u.flatRepo.GetHousePriceStats(ctx, flat.HouseID, lg)
+		if err != nil {
+			lg.Warn("flat usecase: auto-moderation: get house price stats error", zap.Error(err))
+		}
 	}
 
-	return createdFlatResponse, nil
+	verdict := u.autoModerator.Evaluate(flat, stats)
+	if verdict != nil {
+		lg.Info("flat usecase: auto-moderation rule fired", zap.String("rule", verdict.Rule),
+			zap.String("outcome", verdict.Outcome))
+	}
+	return verdict
 }
 
 func (u *FlatUsecase) Update(ctx context.Context, moderatorID uuid.UUID, newFlatData *domain.UpdateFlatRequest, lg *zap.Logger) (domain.CreateFlatResponse, error) {
@@ -101,32 +231,684 @@ func (u *FlatUsecase) Update(ctx context.Context, moderatorID uuid.UUID, newFlat
 			fmt.Errorf("flat usecase: update error: %w", domain.ErrFlat_BadHouseID)
 	}
 
-	if newFlatData.Status != domain.AnyStatus && !IsCorrectFlatStatus(newFlatData.Status) {
+	if newFlatData.Status == domain.AnyStatus || !IsCorrectFlatStatus(newFlatData.Status) {
 		lg.Warn("flat usecase: update error: bad status", zap.String("status", newFlatData.Status))
 		return domain.CreateFlatResponse{},
 			fmt.Errorf("flat usecase: update error: %w", domain.ErrFlat_BadStatus)
 	}
 
-	flat := domain.Flat{
-		ID:      newFlatData.ID,
-		HouseID: newFlatData.HouseID,
-		Status:  newFlatData.Status,
+	err := u.checkDecision(newFlatData)
+	if err != nil {
+		lg.Warn("flat usecase: update error: bad decision", zap.Error(err),
+			zap.String("reason_code", newFlatData.ReasonCode))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: update error: %w", err)
+	}
+
+	currentFlat, err := u.flatRepo.GetByID(ctx, newFlatData.ID, newFlatData.HouseID, lg)
+	if err != nil {
+		lg.Warn("flat usecase: update error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: update error: %w", err)
+	}
+
+	err = checkVersion(&currentFlat, newFlatData.Version)
+	if err != nil {
+		lg.Warn("flat usecase: update error", zap.Error(err), zap.Int("version", currentFlat.Version))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: update error: %w", err)
+	}
+
+	if currentFlat.Status == domain.ArchivedStatus {
+		lg.Warn("flat usecase: update error: flat is archived")
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: update error: %w", domain.ErrFlat_Archived)
+	}
+
+	err = CheckModeratorTransition(&currentFlat, newFlatData.Status, moderatorID)
+	if err != nil {
+		lg.Warn("flat usecase: update error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: update error: %w", err)
+	}
+
+	flat := currentFlat
+	flat.Status = newFlatData.Status
+	flat.ModeratorID = moderatorAfter(newFlatData.Status, moderatorID)
+	flat.ModerationExpiresAt = time.Time{}
+	if flat.Status == domain.ModeratingStatus {
+		flat.ModerationExpiresAt = time.Now().Add(u.moderationLease)
 	}
 
-	updatedFlat, err := u.flatRepo.Update(ctx, moderatorID, &flat, lg)
+	var decision *domain.ModerationDecision
+	if flat.Status == domain.ApprovedStatus || flat.Status == domain.DeclinedStatus {
+		decision = &domain.ModerationDecision{
+			FlatID:      flat.ID,
+			HouseID:     flat.HouseID,
+			ModeratorID: moderatorID,
+			Decision:    flat.Status,
+			ReasonCode:  newFlatData.ReasonCode,
+			Comment:     newFlatData.Comment,
+		}
+	}
+
+	updatedFlat, err := u.flatRepo.Update(ctx, moderatorID, &currentFlat, &flat, decision, lg)
+	err = versionRaceError(err, newFlatData.Version)
 	if err != nil {
 		lg.Warn("flat usecase: update error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: update error: %w", err)
+	}
+
+	return toCreateFlatResponse(&updatedFlat), nil
+}
+
+func toSingleFlatResponse(flat *domain.Flat) domain.SingleFlatResponse {
+	return domain.SingleFlatResponse{
+		ID:            flat.ID,
+		HouseID:       flat.HouseID,
+		Price:         flat.Price,
+		Rooms:         flat.Rooms,
+		Status:        flat.Status,
+		TotalArea:     flat.TotalArea,
+		LivingArea:    flat.LivingArea,
+		Floor:         flat.Floor,
+		Floors:        flat.Floors,
+		Description:   flat.Description,
+		Layout:        flat.Layout,
+		Balcony:       flat.Balcony,
+		PricePerMeter: pricePerMeter(flat),
+		Version:       flat.Version,
+		DuplicateOf:   flat.DuplicateOf,
+	}
+}
+
+// checkVersion compares the If-Match version sent by the client with the stored one; 0 means no precondition.
+func checkVersion(flat *domain.Flat, version int) error {
+	if version != 0 && version != flat.Version {
+		return domain.ErrFlat_VersionMismatch
+	}
+	return nil
+}
+
+// versionRaceError reports a flat changed between the version check and the write as a failed precondition
+// when the client sent If-Match: the version it relied on is gone either way.
+func versionRaceError(err error, version int) error {
+	if version != 0 && errors.Is(err, domain.ErrFlat_Conflict) {
+		return domain.ErrFlat_VersionMismatch
+	}
+	return err
+}
+
+func pageLimit(limit int) (int, error) {
+	if limit == 0 {
+		return domain.DefaultPageLimit, nil
+	}
+	if limit < 0 || limit > domain.MaxPageLimit {
+		return 0, domain.ErrFlat_BadLimit
+	}
+	return limit, nil
+}
+
+func (u *FlatUsecase) Search(ctx context.Context, req *domain.FlatSearchRequest, lg *zap.Logger) (domain.FlatSearchResponse, error) {
+	lg.Info("flat usecase: search")
+
+	if req == nil {
+		lg.Warn("flat usecase: search error: bad request = nil")
+		return domain.FlatSearchResponse{},
+			fmt.Errorf("flat usecase: search error: %w", domain.ErrFlat_BadRequest)
+	}
+
+	if req.PriceMin < 0 || req.PriceMax < 0 || req.Rooms < 0 || req.HouseID < 0 ||
+		req.YearMin < 0 || req.YearMax < 0 ||
+		(req.PriceMax > 0 && req.PriceMin > req.PriceMax) ||
+		(req.YearMax > 0 && req.YearMin > req.YearMax) {
+		lg.Warn("flat usecase: search error: bad filter")
+		return domain.FlatSearchResponse{},
+			fmt.Errorf("flat usecase: search error: %w", domain.ErrFlat_BadFilter)
+	}
+
+	sort := req.Sort
+	if sort == "" {
+		sort = domain.SortByPrice
+	}
+	if sort != domain.SortByPrice && sort != domain.SortByDate {
+		lg.Warn("flat usecase: search error: bad sort", zap.String("sort", req.Sort))
+		return domain.FlatSearchResponse{},
+			fmt.Errorf("flat usecase: search error: %w", domain.ErrFlat_BadSort)
+	}
+
+	limit, err := pageLimit(req.Limit)
+	if err != nil {
+		lg.Warn("flat usecase: search error: bad limit", zap.Int("limit", req.Limit))
+		return domain.FlatSearchResponse{}, fmt.Errorf("flat usecase: search error: %w", err)
+	}
+
+	filter := domain.FlatSearchFilter{
+		PriceMin:  req.PriceMin,
+		PriceMax:  req.PriceMax,
+		Rooms:     req.Rooms,
+		HouseID:   req.HouseID,
+		Developer: req.Developer,
+		YearMin:   req.YearMin,
+		YearMax:   req.YearMax,
+		Sort:      sort,
+		Limit:     limit + 1,
+	}
+
+	if req.Cursor != "" {
+		var cursor domain.FlatSearchCursor
+		err = pkg.DecodeCursor(req.Cursor, &cursor)
+		if err != nil || cursor.Sort != sort {
+			lg.Warn("flat usecase: search error: bad cursor", zap.String("cursor", req.Cursor))
+			return domain.FlatSearchResponse{},
+				fmt.Errorf("flat usecase: search error: %w", domain.ErrFlat_BadCursor)
+		}
+		filter.After = &cursor
+	}
+
+	flats, err := u.flatRepo.Search(ctx, &filter, lg)
+	if err != nil {
+		lg.Warn("flat usecase: search error", zap.Error(err))
+		return domain.FlatSearchResponse{}, fmt.Errorf("flat usecase: search error: %v", err.Error())
+	}
+
+	var response domain.FlatSearchResponse
+	if len(flats) > limit {
+		flats = flats[:limit]
+		last := flats[limit-1]
+		response.NextCursor, err = pkg.EncodeCursor(domain.FlatSearchCursor{
+			Sort:    sort,
+			Price:   last.Price,
+			Date:    last.CreateFlatDate,
+			HouseID: last.HouseID,
+			FlatID:  last.ID,
+		})
+		if err != nil {
+			lg.Warn("flat usecase: search error: encode cursor", zap.Error(err))
+			return domain.FlatSearchResponse{}, fmt.Errorf("flat usecase: search error: %v", err.Error())
+		}
+	}
+
+	response.Flats = make([]domain.SingleFlatResponse, 0, len(flats))
+	for i := range flats {
+		response.Flats = append(response.Flats, toSingleFlatResponse(&flats[i]))
+	}
+
+	return response, nil
+}
+
+func toCreateFlatResponse(flat *domain.Flat) domain.CreateFlatResponse {
+	var expiresAt string
+	if !flat.ModerationExpiresAt.IsZero() {
+		expiresAt = flat.ModerationExpiresAt.Format(time.DateTime)
+	}
+
+	return domain.CreateFlatResponse{
+		ID:            flat.ID,
+		HouseID:       flat.HouseID,
+		Price:         flat.Price,
+		Rooms:         flat.Rooms,
+		Status:        flat.Status,
+		TotalArea:     flat.TotalArea,
+		LivingArea:    flat.LivingArea,
+		Floor:         flat.Floor,
+		Floors:        flat.Floors,
+		Description:   flat.Description,
+		Layout:        flat.Layout,
+		Balcony:       flat.Balcony,
+		PricePerMeter: pricePerMeter(flat),
+		Version:       flat.Version,
+		DuplicateOf:   flat.DuplicateOf,
+
+		ModerationExpiresAt: expiresAt,
+	}
+}
+
+func flatChanges(oldFlat *domain.Flat, newFlat *domain.Flat) map[string]domain.FlatFieldChange {
+	changes := make(map[string]domain.FlatFieldChange)
+	if oldFlat.Price != newFlat.Price {
+		changes["price"] = domain.FlatFieldChange{Old: oldFlat.Price, New: newFlat.Price}
+	}
+	if oldFlat.Rooms != newFlat.Rooms {
+		changes["rooms"] = domain.FlatFieldChange{Old: oldFlat.Rooms, New: newFlat.Rooms}
+	}
+	if oldFlat.TotalArea != newFlat.TotalArea {
+		changes["total_area"] = domain.FlatFieldChange{Old: oldFlat.TotalArea, New: newFlat.TotalArea}
+	}
+	if oldFlat.LivingArea != newFlat.LivingArea {
+		changes["living_area"] = domain.FlatFieldChange{Old: oldFlat.LivingArea, New: newFlat.LivingArea}
+	}
+	if oldFlat.Floor != newFlat.Floor {
+		changes["floor"] = domain.FlatFieldChange{Old: oldFlat.Floor, New: newFlat.Floor}
+	}
+	if oldFlat.Floors != newFlat.Floors {
+		changes["floors"] = domain.FlatFieldChange{Old: oldFlat.Floors, New: newFlat.Floors}
+	}
+	if oldFlat.Description != newFlat.Description {
+		changes["description"] = domain.FlatFieldChange{Old: oldFlat.Description, New: newFlat.Description}
+	}
+	if oldFlat.Layout != newFlat.Layout {
+		changes["layout"] = domain.FlatFieldChange{Old: oldFlat.Layout, New: newFlat.Layout}
+	}
+	if oldFlat.Balcony != newFlat.Balcony {
+		changes["balcony"] = domain.FlatFieldChange{Old: oldFlat.Balcony, New: newFlat.Balcony}
+	}
+	return changes
+}
+
+func (u *FlatUsecase) Edit(ctx context.Context, userID uuid.UUID, req *domain.EditFlatRequest, lg *zap.Logger) (domain.CreateFlatResponse, error) {
+	lg.Info("flat usecase: edit")
+
+	if req == nil {
+		lg.Warn("flat usecase: edit error: bad request = nil")
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: edit error: %w", domain.ErrFlat_BadRequest)
+	}
+
+	if req.ID < 1 {
+		lg.Warn("flat usecase: edit error: bad flat id", zap.Int("flat_id", req.ID))
 		return domain.CreateFlatResponse{},
-			fmt.Errorf("flat usecase: update error: %v", err.Error())
+			fmt.Errorf("flat usecase: edit error: %w", domain.ErrFlat_BadID)
 	}
 
-	updatedFlatResponse := domain.CreateFlatResponse{
-		ID:      updatedFlat.ID,
-		HouseID: updatedFlat.HouseID,
-		Price:   updatedFlat.Price,
-		Rooms:   updatedFlat.Rooms,
-		Status:  updatedFlat.Status,
+	if req.HouseID < 1 {
+		lg.Warn("flat usecase: edit error: bad house id", zap.Int("house_id", req.HouseID))
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: edit error: %w", domain.ErrFlat_BadHouseID)
 	}
 
-	return updatedFlatResponse, nil
+	if req.Rooms != nil && *req.Rooms < 1 {
+		lg.Warn("flat usecase: edit error: bad rooms", zap.Int("rooms", *req.Rooms))
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: edit error: %w", domain.ErrFlat_BadRooms)
+	}
+
+	if req.Price != nil && *req.Price < 0 {
+		lg.Warn("flat usecase: edit error: bad price", zap.Int("price", *req.Price))
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: edit error: %w", domain.ErrFlat_BadPrice)
+	}
+
+	flat, err := u.flatRepo.GetByID(ctx, req.ID, req.HouseID, lg)
+	if err != nil {
+		lg.Warn("flat usecase: edit error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: edit error: %w", err)
+	}
+
+	if flat.UserID != userID {
+		lg.Warn("flat usecase: edit error: not owner", zap.String("user_id", userID.String()))
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: edit error: %w", domain.ErrFlat_NotOwner)
+	}
+
+	err = checkVersion(&flat, req.Version)
+	if err != nil {
+		lg.Warn("flat usecase: edit error", zap.Error(err), zap.Int("version", flat.Version))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: edit error: %w", err)
+	}
+
+	if flat.Status == domain.ModeratingStatus {
+		lg.Warn("flat usecase: edit error: flat is on moderation")
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: edit error: %w", domain.ErrFlat_OnModeration)
+	}
+
+	if flat.Status == domain.ArchivedStatus {
+		lg.Warn("flat usecase: edit error: flat is archived")
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: edit error: %w", domain.ErrFlat_Archived)
+	}
+
+	err = CheckFlatTransition(flat.Status, domain.CreatedStatus, OwnerActor)
+	if err != nil {
+		lg.Warn("flat usecase: edit error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: edit error: %w", err)
+	}
+
+	newFlat := flat
+	if req.Price != nil {
+		newFlat.Price = *req.Price
+	}
+	if req.Rooms != nil {
+		newFlat.Rooms = *req.Rooms
+	}
+	if req.TotalArea != nil {
+		newFlat.TotalArea = *req.TotalArea
+	}
+	if req.LivingArea != nil {
+		newFlat.LivingArea = *req.LivingArea
+	}
+	if req.Floor != nil {
+		newFlat.Floor = *req.Floor
+	}
+	if req.Floors != nil {
+		newFlat.Floors = *req.Floors
+	}
+	if req.Description != nil {
+		newFlat.Description = *req.Description
+	}
+	if req.Layout != nil {
+		newFlat.Layout = *req.Layout
+	}
+	if req.Balcony != nil {
+		newFlat.Balcony = *req.Balcony
+	}
+
+	err = validateFlatAttributes(&newFlat)
+	if err != nil {
+		lg.Warn("flat usecase: edit error: bad attributes", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: edit error: %w", err)
+	}
+
+	changes := flatChanges(&flat, &newFlat)
+	if len(changes) == 0 {
+		return toCreateFlatResponse(&flat), nil
+	}
+
+	edit := domain.FlatEdit{
+		FlatID:  flat.ID,
+		HouseID: flat.HouseID,
+		UserID:  userID,
+		Changes: changes,
+	}
+
+	editedFlat, err := u.flatRepo.Edit(ctx, &flat, &newFlat, &edit, lg)
+	err = versionRaceError(err, req.Version)
+	if err != nil {
+		lg.Warn("flat usecase: edit error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: edit error: %w", err)
+	}
+
+	return toCreateFlatResponse(&editedFlat), nil
+}
+
+func (u *FlatUsecase) GetEdits(ctx context.Context, flatID int, houseID int, lg *zap.Logger) (domain.FlatEditsResponse, error) {
+	lg.Info("flat usecase: get edits")
+
+	if flatID < 1 {
+		lg.Warn("flat usecase: get edits error: bad flat id", zap.Int("flat_id", flatID))
+		return domain.FlatEditsResponse{},
+			fmt.Errorf("flat usecase: get edits error: %w", domain.ErrFlat_BadID)
+	}
+
+	if houseID < 1 {
+		lg.Warn("flat usecase: get edits error: bad house id", zap.Int("house_id", houseID))
+		return domain.FlatEditsResponse{},
+			fmt.Errorf("flat usecase: get edits error: %w", domain.ErrFlat_BadHouseID)
+	}
+
+	edits, err := u.flatRepo.GetEdits(ctx, flatID, houseID, lg)
+	if err != nil {
+		lg.Warn("flat usecase: get edits error", zap.Error(err))
+		return domain.FlatEditsResponse{}, fmt.Errorf("flat usecase: get edits error: %v", err.Error())
+	}
+
+	response := domain.FlatEditsResponse{Edits: make([]domain.FlatEditResponse, 0, len(edits))}
+	for _, edit := range edits {
+		response.Edits = append(response.Edits, domain.FlatEditResponse{
+			ID:       edit.ID,
+			UserID:   edit.UserID,
+			Changes:  edit.Changes,
+			EditedAt: edit.EditDate.Format(time.DateTime),
+		})
+	}
+
+	return response, nil
+}
+
+func (u *FlatUsecase) getManagedFlat(ctx context.Context, userID uuid.UUID, role string,
+	req *domain.ArchiveFlatRequest, lg *zap.Logger) (domain.Flat, error) {
+	if req == nil {
+		lg.Warn("flat usecase: bad request = nil")
+		return domain.Flat{}, domain.ErrFlat_BadRequest
+	}
+
+	if req.ID < 1 {
+		lg.Warn("flat usecase: bad flat id", zap.Int("flat_id", req.ID))
+		return domain.Flat{}, domain.ErrFlat_BadID
+	}
+
+	if req.HouseID < 1 {
+		lg.Warn("flat usecase: bad house id", zap.Int("house_id", req.HouseID))
+		return domain.Flat{}, domain.ErrFlat_BadHouseID
+	}
+
+	flat, err := u.flatRepo.GetByID(ctx, req.ID, req.HouseID, lg)
+	if err != nil {
+		return domain.Flat{}, err
+	}
+
+	if role != domain.Moderator && flat.UserID != userID {
+		lg.Warn("flat usecase: not owner", zap.String("user_id", userID.String()))
+		return domain.Flat{}, domain.ErrFlat_NotOwner
+	}
+
+	err = checkVersion(&flat, req.Version)
+	if err != nil {
+		lg.Warn("flat usecase: stale version", zap.Int("version", flat.Version))
+		return domain.Flat{}, err
+	}
+
+	return flat, nil
+}
+
+func (u *FlatUsecase) Archive(ctx context.Context, userID uuid.UUID, role string,
+	req *domain.ArchiveFlatRequest, lg *zap.Logger) (domain.CreateFlatResponse, error) {
+	lg.Info("flat usecase: archive")
+
+	flat, err := u.getManagedFlat(ctx, userID, role, req, lg)
+	if err != nil {
+		lg.Warn("flat usecase: archive error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: archive error: %w", err)
+	}
+
+	if flat.Status == domain.ArchivedStatus {
+		lg.Warn("flat usecase: archive error: flat already archived")
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: archive error: %w", domain.ErrFlat_Archived)
+	}
+
+	err = CheckFlatTransition(flat.Status, domain.ArchivedStatus, flatActor(&flat, userID))
+	if err != nil {
+		lg.Warn("flat usecase: archive error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: archive error: %w", err)
+	}
+
+	archivedFlat, err := u.flatRepo.Archive(ctx, userID, &flat, lg)
+	err = versionRaceError(err, req.Version)
+	if err != nil {
+		lg.Warn("flat usecase: archive error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: archive error: %w", err)
+	}
+
+	return toCreateFlatResponse(&archivedFlat), nil
+}
+
+func (u *FlatUsecase) Restore(ctx context.Context, userID uuid.UUID, role string,
+	req *domain.ArchiveFlatRequest, lg *zap.Logger) (domain.CreateFlatResponse, error) {
+	lg.Info("flat usecase: restore")
+
+	flat, err := u.getManagedFlat(ctx, userID, role, req, lg)
+	if err != nil {
+		lg.Warn("flat usecase: restore error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: restore error: %w", err)
+	}
+
+	if flat.Status != domain.ArchivedStatus {
+		lg.Warn("flat usecase: restore error: flat is not archived")
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("flat usecase: restore error: %w", domain.ErrFlat_NotArchived)
+	}
+
+	restoredFlat, err := u.flatRepo.Restore(ctx, userID, &flat, lg)
+	err = versionRaceError(err, req.Version)
+	if err != nil {
+		lg.Warn("flat usecase: restore error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("flat usecase: restore error: %w", err)
+	}
+
+	return toCreateFlatResponse(&restoredFlat), nil
+}
+
+func (u *FlatUsecase) GetDetail(ctx context.Context, userID uuid.UUID, role string, flatID int, houseID int,
+	lg *zap.Logger) (domain.FlatDetailResponse, error) {
+	lg.Info("flat usecase: get detail")
+
+	if flatID < 1 {
+		lg.Warn("flat usecase: get detail error: bad flat id", zap.Int("flat_id", flatID))
+		return domain.FlatDetailResponse{},
+			fmt.Errorf("flat usecase: get detail error: %w", domain.ErrFlat_BadID)
+	}
+
+	if houseID < 1 {
+		lg.Warn("flat usecase: get detail error: bad house id", zap.Int("house_id", houseID))
+		return domain.FlatDetailResponse{},
+			fmt.Errorf("flat usecase: get detail error: %w", domain.ErrFlat_BadHouseID)
+	}
+
+	flat, err := u.flatRepo.GetByID(ctx, flatID, houseID, lg)
+	if err != nil {
+		lg.Warn("flat usecase: get detail error", zap.Error(err))
+		return domain.FlatDetailResponse{}, fmt.Errorf("flat usecase: get detail error: %w", err)
+	}
+
+	if role != domain.Moderator && flat.UserID != userID {
+		lg.Warn("flat usecase: get detail error: not owner", zap.String("user_id", userID.String()))
+		return domain.FlatDetailResponse{},
+			fmt.Errorf("flat usecase: get detail error: %w", domain.ErrFlat_NotOwner)
+	}
+
+	decision, err := u.flatRepo.GetLastDecision(ctx, flatID, houseID, lg)
+	if err != nil {
+		lg.Warn("flat usecase: get detail error", zap.Error(err))
+		return domain.FlatDetailResponse{}, fmt.Errorf("flat usecase: get detail error: %v", err.Error())
+	}
+
+	response := domain.FlatDetailResponse{SingleFlatResponse: toSingleFlatResponse(&flat)}
+	if decision != nil {
+		response.LastDecision = u.toDecisionResponse(decision)
+	}
+
+	return response, nil
+}
+
+func (u *FlatUsecase) GetHistory(ctx context.Context, userID uuid.UUID, role string, flatID int, houseID int,
+	lg *zap.Logger) (domain.FlatHistoryResponse, error) {
+	lg.Info("flat usecase: get history")
+
+	flat, err := u.getManagedFlat(ctx, userID, role, &domain.ArchiveFlatRequest{ID: flatID, HouseID: houseID}, lg)
+	if err != nil {
+		lg.Warn("flat usecase: get history error", zap.Error(err))
+		return domain.FlatHistoryResponse{}, fmt.Errorf("flat usecase: get history error: %w", err)
+	}
+
+	history, err := u.flatRepo.GetStatusHistory(ctx, flatID, houseID, lg)
+	if err != nil {
+		lg.Warn("flat usecase: get history error", zap.Error(err))
+		return domain.FlatHistoryResponse{}, fmt.Errorf("flat usecase: get history error: %v", err.Error())
+	}
+
+	response := domain.FlatHistoryResponse{History: make([]domain.FlatStatusChangeResponse, 0, len(history))}
+	for _, change := range history {
+		response.History = append(response.History, toStatusChangeResponse(&change, &flat, role))
+	}
+
+	return response, nil
+}
+
+func (u *FlatUsecase) GetOwnFlats(ctx context.Context, userID uuid.UUID, lg *zap.Logger) (domain.OwnerFlatsResponse, error) {
+	lg.Info("flat usecase: get own flats", zap.String("user_id", userID.String()))
+
+	flats, err := u.flatRepo.GetByOwner(ctx, userID, lg)
+	if err != nil {
+		lg.Warn("flat usecase: get own flats error", zap.Error(err))
+		return domain.OwnerFlatsResponse{}, fmt.Errorf("flat usecase: get own flats error: %v", err.Error())
+	}
+
+	response := domain.OwnerFlatsResponse{Flats: make([]domain.FlatDetailResponse, 0, len(flats))}
+	for _, ownerFlat := range flats {
+		flatResponse := domain.FlatDetailResponse{SingleFlatResponse: toSingleFlatResponse(&ownerFlat.Flat)}
+		if ownerFlat.LastDecision != nil {
+			flatResponse.LastDecision = u.toDecisionResponse(ownerFlat.LastDecision)
+		}
+		response.Flats = append(response.Flats, flatResponse)
+	}
+
+	return response, nil
+}
+
+// BackfillDuplicates links already stored flats to the listings they duplicate, batchSize flats at a time.
+// It returns the number of flats marked as duplicates.
+func (u *FlatUsecase) BackfillDuplicates(ctx context.Context, batchSize int, lg *zap.Logger) (int, error) {
+	lg.Info("flat usecase: backfill duplicates", zap.Int("batch_size", batchSize))
+
+	var afterHouseID, afterFlatID, marked int
+	for {
+		flats, err := u.flatRepo.GetUnlinkedBatch(ctx, afterHouseID, afterFlatID, batchSize, lg)
+		if err != nil {
+			lg.Warn("flat usecase: backfill duplicates error", zap.Error(err))
+			return marked, fmt.Errorf("flat usecase: backfill duplicates error: %v", err.Error())
+		}
+		if len(flats) == 0 {
+			return marked, nil
+		}
+
+		for i := range flats {
+			originalID, err := u.flatRepo.FindDuplicate(ctx, &flats[i], lg)
+			if err != nil {
+				lg.Warn("flat usecase: backfill duplicates error", zap.Error(err))
+				return marked, fmt.Errorf("flat usecase: backfill duplicates error: %v", err.Error())
+			}
+			if originalID == 0 {
+				continue
+			}
+
+			err = u.flatRepo.SetDuplicateOf(ctx, &flats[i], originalID, lg)
+			if err != nil {
+				lg.Warn("flat usecase: backfill duplicates error", zap.Error(err))
+				return marked, fmt.Errorf("flat usecase: backfill duplicates error: %v", err.Error())
+			}
+			marked++
+		}
+
+		last := flats[len(flats)-1]
+		afterHouseID, afterFlatID = last.HouseID, last.ID
+		lg.Info("flat usecase: backfill duplicates: batch done", zap.Int("house_id", afterHouseID),
+			zap.Int("flat_id", afterFlatID), zap.Int("marked", marked))
+	}
+}
+
+// toStatusChangeResponse hides moderator identities from owners: they only see who acted, not which moderator.
+func toStatusChangeResponse(change *domain.FlatStatusChange, flat *domain.Flat, role string) domain.FlatStatusChangeResponse {
+	response := domain.FlatStatusChangeResponse{
+		FromStatus: change.FromStatus,
+		ToStatus:   change.ToStatus,
+		Comment:    change.Comment,
+		ChangedAt:  change.ChangeDate.Format(time.DateTime),
+	}
+
+	switch {
+	case change.ActorID == uuid.Nil:
+		response.Actor = domain.SystemActorKind
+	case change.ActorID == flat.UserID:
+		response.Actor = domain.OwnerActorKind
+		response.ActorID = change.ActorID.String()
+	default:
+		response.Actor = domain.ModeratorActorKind
+		if role == domain.Moderator {
+			response.ActorID = change.ActorID.String()
+		}
+	}
+
+	return response
+}
+
+func (u *FlatUsecase) toDecisionResponse(decision *domain.ModerationDecision) *domain.ModerationDecisionResponse {
+	response := domain.ModerationDecisionResponse{
+		Decision:   decision.Decision,
+		ReasonCode: decision.ReasonCode,
+		Comment:    decision.Comment,
+		DecidedAt:  decision.DecisionDate.Format(time.DateTime),
+	}
+	// a reason removed from the catalog is still shown by its code
+	if reason, ok := u.findDeclineReason(decision.ReasonCode); ok {
+		response.Reason = reason.Title
+	}
+	return &response
 }
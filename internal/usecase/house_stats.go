@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"avito-test-task/internal/domain"
+	"math"
+	"sort"
+)
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
+
+// medianPrice takes buckets ordered by price and averages the two middle prices for an even count.
+func medianPrice(buckets []domain.HousePriceBucket, flats int) float64 {
+	lower, upper := (flats+1)/2, flats/2+1
+	var (
+		seen                   int
+		lowerPrice, upperPrice int
+	)
+	for _, bucket := range buckets {
+		if seen < lower && seen+bucket.Flats >= lower {
+			lowerPrice = bucket.Price
+		}
+		if seen < upper && seen+bucket.Flats >= upper {
+			upperPrice = bucket.Price
+			break
+		}
+		seen += bucket.Flats
+	}
+
+	return float64(lowerPrice+upperPrice) / 2
+}
+
+func roomsStats(rooms int, buckets []domain.HousePriceBucket) domain.HouseRoomsStatsResponse {
+	stats := domain.HouseRoomsStatsResponse{
+		Rooms:    rooms,
+		PriceMin: buckets[0].Price,
+		PriceMax: buckets[len(buckets)-1].Price,
+	}
+	var total float64
+	for _, bucket := range buckets {
+		stats.Flats += bucket.Flats
+		total += float64(bucket.Price) * float64(bucket.Flats)
+	}
+	stats.PriceMedian = medianPrice(buckets, stats.Flats)
+	stats.PriceAvg = roundPrice(total / float64(stats.Flats))
+
+	return stats
+}
+
+// houseStats aggregates the approved flats counters of a house; buckets must be ordered by price.
+// The average price per room is the total price of the flats divided by their total rooms.
+func houseStats(houseID int, buckets []domain.HousePriceBucket) domain.HouseStatsResponse {
+	stats := domain.HouseStatsResponse{
+		HouseID: houseID,
+		Rooms:   []domain.HouseRoomsStatsResponse{},
+	}
+	if len(buckets) == 0 {
+		return stats
+	}
+
+	var totalPrice, totalRooms float64
+	byRooms := make(map[int][]domain.HousePriceBucket)
+	for _, bucket := range buckets {
+		stats.ApprovedFlats += bucket.Flats
+		totalPrice += float64(bucket.Price) * float64(bucket.Flats)
+		totalRooms += float64(bucket.Rooms) * float64(bucket.Flats)
+		byRooms[bucket.Rooms] = append(byRooms[bucket.Rooms], bucket)
+	}
+	stats.PriceMin = buckets[0].Price
+	stats.PriceMax = buckets[len(buckets)-1].Price
+	stats.PriceMedian = medianPrice(buckets, stats.ApprovedFlats)
+	if totalRooms > 0 {
+		stats.AvgPricePerRoom = roundPrice(totalPrice / totalRooms)
+	}
+
+	for rooms, roomsBuckets := range byRooms {
+		stats.Rooms = append(stats.Rooms, roomsStats(rooms, roomsBuckets))
+	}
+	sort.Slice(stats.Rooms, func(i, j int) bool {
+		return stats.Rooms[i].Rooms < stats.Rooms[j].Rooms
+	})
+
+	return stats
+}
@@ -2,26 +2,34 @@ package usecase
 
 import (
 	"avito-test-task/internal/domain"
+	"avito-test-task/pkg"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"math"
+	"strings"
 	"sync"
 	"time"
 )
 
 type HouseUsecase struct {
-	houseRepo    domain.HouseRepo
-	notifySender domain.NotifySender
-	notifyRepo   domain.NotifyRepo
+	houseRepo      domain.HouseRepo
+	notifySender   domain.NotifySender
+	notifyRepo     domain.NotifyRepo
+	unsubscribeURL string
 }
 
-func NewHouseUsecase(houseRepo domain.HouseRepo, notifySender domain.NotifySender,
-	notifyRepo domain.NotifyRepo, done chan bool, freq time.Duration, timeout time.Duration, lg *zap.Logger) *HouseUsecase {
+// NewHouseUsecase starts the notifying goroutine; unsubscribeURL is the public /unsubscribe address
+// linked from notifications, an empty one leaves the link out.
+func NewHouseUsecase(houseRepo domain.HouseRepo, notifySender domain.NotifySender, notifyRepo domain.NotifyRepo,
+	unsubscribeURL string, done chan bool, freq time.Duration, timeout time.Duration, lg *zap.Logger) *HouseUsecase {
 	houseUsecase := HouseUsecase{
-		houseRepo:    houseRepo,
-		notifySender: notifySender,
-		notifyRepo:   notifyRepo,
+		houseRepo:      houseRepo,
+		notifySender:   notifySender,
+		notifyRepo:     notifyRepo,
+		unsubscribeURL: unsubscribeURL,
 	}
 
 	go houseUsecase.Notifying(done, freq, timeout, lg)
@@ -44,94 +52,474 @@ func (u *HouseUsecase) Create(ctx context.Context, req *domain.CreateHouseReques
 			fmt.Errorf("house usecase: create error: %w", domain.ErrHouse_BadYear)
 	}
 
+	if req.DeveloperID < 1 {
+		lg.Warn("house usecase: create error: bad developer id", zap.Int("developer_id", req.DeveloperID))
+		return domain.CreateHouseResponse{},
+			fmt.Errorf("house usecase: create error: %w", domain.ErrDeveloper_BadID)
+	}
+
+	err := checkLocation(req.Latitude, req.Longitude)
+	if err != nil {
+		lg.Warn("house usecase: create error: bad location")
+		return domain.CreateHouseResponse{}, fmt.Errorf("house usecase: create error: %w", err)
+	}
+
 	date := time.Now()
 
 	house := domain.House{
-		HouseID:         req.HomeID,
-		Address:         req.Address,
-		ConstructYear:   req.Year,
-		Developer:       req.Developer,
-		CreateHouseDate: date,
-		UpdateFlatDate:  date,
+		HouseID:           req.HomeID,
+		Address:           req.Address,
+		ConstructYear:     req.Year,
+		DeveloperID:       req.DeveloperID,
+		CreateHouseDate:   date,
+		UpdateFlatDate:    date,
+		Latitude:          req.Latitude,
+		Longitude:         req.Longitude,
+		NormalizedAddress: NormalizeAddress(req.Address),
 	}
 
-	house, err := u.houseRepo.Create(ctx, &house, lg)
+	house, err = u.houseRepo.Create(ctx, &house, lg)
 	if err != nil {
-		lg.Warn("user usecase: create error", zap.Error(err))
-		return domain.CreateHouseResponse{}, fmt.Errorf("user usecase: create error: %v", err.Error())
+		lg.Warn("house usecase: create error", zap.Error(err))
+		return domain.CreateHouseResponse{}, fmt.Errorf("house usecase: create error: %w", err)
 	}
 
-	houseResponse := domain.CreateHouseResponse{
-		HomeID:    house.HouseID,
-		Address:   house.Address,
-		Year:      house.ConstructYear,
-		Developer: house.Developer,
-		CreatedAt: house.CreateHouseDate.Format(time.DateTime),
-		UpdateAt:  house.UpdateFlatDate.Format(time.DateTime),
+	return toCreateHouseResponse(&house), nil
+}
+
+func toCreateHouseResponse(house *domain.House) domain.CreateHouseResponse {
+	return domain.CreateHouseResponse{
+		HomeID:      house.HouseID,
+		Address:     house.Address,
+		Year:        house.ConstructYear,
+		DeveloperID: house.DeveloperID,
+		Developer:   house.Developer,
+		CreatedAt:   house.CreateHouseDate.Format(time.DateTime),
+		UpdateAt:    house.UpdateFlatDate.Format(time.DateTime),
+		Version:     house.Version,
+		Latitude:    house.Latitude,
+		Longitude:   house.Longitude,
 	}
+}
 
-	return houseResponse, nil
+func isLatitude(latitude float64) bool {
+	return latitude >= -90 && latitude <= 90
 }
 
-func parallelFlatFilter(flats []domain.Flat, lg *zap.Logger) domain.FlatsByHouseResponse {
-	var (
-		flatsArr []domain.SingleFlatResponse
-	)
+func isLongitude(longitude float64) bool {
+	return longitude >= -180 && longitude <= 180
+}
 
-	lenOfPart := len(flats) / 3
-	parts := make([][]domain.Flat, 0)
-	for i := 0; i < 2; i++ {
-		parts = append(parts, flats[i*lenOfPart:(i+1)*lenOfPart])
+// checkLocation accepts either no coordinates or a valid latitude and longitude pair.
+func checkLocation(latitude *float64, longitude *float64) error {
+	if latitude == nil && longitude == nil {
+		return nil
+	}
+	if latitude == nil || longitude == nil || !isLatitude(*latitude) || !isLongitude(*longitude) {
+		return domain.ErrHouse_BadLocation
+	}
+	return nil
+}
+
+func checkGeoBox(box *domain.GeoBox) error {
+	if !isLatitude(box.MinLat) || !isLatitude(box.MaxLat) || box.MinLat > box.MaxLat ||
+		!isLongitude(box.MinLon) || !isLongitude(box.MaxLon) {
+		return domain.ErrHouse_BadLocation
+	}
+	return nil
+}
+
+func checkHouseVersion(house *domain.House, version int) error {
+	if version != 0 && version != house.Version {
+		return domain.ErrHouse_VersionMismatch
+	}
+	return nil
+}
+
+// houseVersionRaceError reports a house changed between the version check and the write
+// as a failed precondition when the client sent If-Match.
+func houseVersionRaceError(err error, version int) error {
+	if version != 0 && errors.Is(err, domain.ErrHouse_Conflict) {
+		return domain.ErrHouse_VersionMismatch
+	}
+	return err
+}
+
+func (u *HouseUsecase) Edit(ctx context.Context, req *domain.EditHouseRequest, lg *zap.Logger) (domain.CreateHouseResponse, error) {
+	lg.Info("house usecase: edit")
+
+	if req == nil {
+		lg.Warn("house usecase: edit error: bad request = nil")
+		return domain.CreateHouseResponse{},
+			fmt.Errorf("house usecase: edit error: %w", domain.ErrHouse_BadRequest)
+	}
+
+	if req.ID < 1 {
+		lg.Warn("house usecase: edit error: bad id", zap.Int("house_id", req.ID))
+		return domain.CreateHouseResponse{},
+			fmt.Errorf("house usecase: edit error: %w", domain.ErrHouse_BadID)
+	}
+
+	if req.Address == nil && req.Year == nil && req.DeveloperID == nil &&
+		req.Latitude == nil && req.Longitude == nil {
+		lg.Warn("house usecase: edit error: nothing to change")
+		return domain.CreateHouseResponse{},
+			fmt.Errorf("house usecase: edit error: %w", domain.ErrHouse_BadEdit)
+	}
+
+	if req.Address != nil && strings.TrimSpace(*req.Address) == "" {
+		lg.Warn("house usecase: edit error: empty address")
+		return domain.CreateHouseResponse{},
+			fmt.Errorf("house usecase: edit error: %w", domain.ErrHouse_BadEdit)
+	}
+
+	if req.Year != nil && *req.Year < 0 {
+		lg.Warn("house usecase: edit error: bad house year", zap.Int("year", *req.Year))
+		return domain.CreateHouseResponse{},
+			fmt.Errorf("house usecase: edit error: %w", domain.ErrHouse_BadYear)
+	}
+
+	if req.DeveloperID != nil && *req.DeveloperID < 1 {
+		lg.Warn("house usecase: edit error: bad developer id", zap.Int("developer_id", *req.DeveloperID))
+		return domain.CreateHouseResponse{},
+			fmt.Errorf("house usecase: edit error: %w", domain.ErrDeveloper_BadID)
+	}
+
+	// coordinates only move together
+	err := checkLocation(req.Latitude, req.Longitude)
+	if err != nil {
+		lg.Warn("house usecase: edit error: bad location")
+		return domain.CreateHouseResponse{}, fmt.Errorf("house usecase: edit error: %w", err)
+	}
+
+	house, err := u.houseRepo.GetByID(ctx, req.ID, lg)
+	if err != nil {
+		lg.Warn("house usecase: edit error", zap.Error(err))
+		return domain.CreateHouseResponse{}, fmt.Errorf("house usecase: edit error: %w", err)
+	}
+
+	err = checkHouseVersion(&house, req.Version)
+	if err != nil {
+		lg.Warn("house usecase: edit error", zap.Error(err), zap.Int("version", house.Version))
+		return domain.CreateHouseResponse{}, fmt.Errorf("house usecase: edit error: %w", err)
+	}
+
+	newHouse := house
+	if req.Address != nil {
+		newHouse.Address = *req.Address
+	}
+	if req.Year != nil {
+		newHouse.ConstructYear = *req.Year
+	}
+	if req.DeveloperID != nil {
+		newHouse.DeveloperID = *req.DeveloperID
+	}
+	if req.Latitude != nil {
+		newHouse.Latitude = req.Latitude
+		newHouse.Longitude = req.Longitude
 	}
-	parts = append(parts, flats[2*lenOfPart:])
+	newHouse.NormalizedAddress = NormalizeAddress(newHouse.Address)
 
-	mtx := sync.Mutex{}
+	newHouse, err = u.houseRepo.Update(ctx, &house, &newHouse, lg)
+	err = houseVersionRaceError(err, req.Version)
+	if err != nil {
+		lg.Warn("house usecase: edit error", zap.Error(err))
+		return domain.CreateHouseResponse{}, fmt.Errorf("house usecase: edit error: %w", err)
+	}
+
+	return toCreateHouseResponse(&newHouse), nil
+}
+
+func (u *HouseUsecase) Delete(ctx context.Context, req *domain.DeleteHouseRequest, lg *zap.Logger) error {
+	lg.Info("house usecase: delete")
+
+	if req == nil {
+		lg.Warn("house usecase: delete error: bad request = nil")
+		return fmt.Errorf("house usecase: delete error: %w", domain.ErrHouse_BadRequest)
+	}
+
+	if req.ID < 1 {
+		lg.Warn("house usecase: delete error: bad id", zap.Int("house_id", req.ID))
+		return fmt.Errorf("house usecase: delete error: %w", domain.ErrHouse_BadID)
+	}
+
+	house, err := u.houseRepo.GetByID(ctx, req.ID, lg)
+	if err != nil {
+		lg.Warn("house usecase: delete error", zap.Error(err))
+		return fmt.Errorf("house usecase: delete error: %w", err)
+	}
+
+	err = checkHouseVersion(&house, req.Version)
+	if err != nil {
+		lg.Warn("house usecase: delete error", zap.Error(err), zap.Int("version", house.Version))
+		return fmt.Errorf("house usecase: delete error: %w", err)
+	}
+
+	err = u.houseRepo.DeleteByID(ctx, &house, lg)
+	err = houseVersionRaceError(err, req.Version)
+	if err != nil {
+		lg.Warn("house usecase: delete error", zap.Error(err))
+		return fmt.Errorf("house usecase: delete error: %w", err)
+	}
+
+	return nil
+}
+
+func (u *HouseUsecase) Search(ctx context.Context, req *domain.HouseSearchRequest, lg *zap.Logger) (domain.HouseSearchResponse, error) {
+	lg.Info("house usecase: search")
+
+	if req == nil {
+		lg.Warn("house usecase: search error: bad request = nil")
+		return domain.HouseSearchResponse{},
+			fmt.Errorf("house usecase: search error: %w", domain.ErrHouse_BadRequest)
+	}
+
+	if req.DeveloperID < 0 || req.YearMin < 0 || req.YearMax < 0 || (req.YearMax > 0 && req.YearMin > req.YearMax) {
+		lg.Warn("house usecase: search error: bad filter", zap.Int("developer_id", req.DeveloperID),
+			zap.Int("year_min", req.YearMin), zap.Int("year_max", req.YearMax))
+		return domain.HouseSearchResponse{},
+			fmt.Errorf("house usecase: search error: %w", domain.ErrHouse_BadFilter)
+	}
+
+	if req.Box != nil && checkGeoBox(req.Box) != nil {
+		lg.Warn("house usecase: search error: bad box", zap.Float64("min_lat", req.Box.MinLat),
+			zap.Float64("max_lat", req.Box.MaxLat), zap.Float64("min_lon", req.Box.MinLon),
+			zap.Float64("max_lon", req.Box.MaxLon))
+		return domain.HouseSearchResponse{},
+			fmt.Errorf("house usecase: search error: %w", domain.ErrHouse_BadLocation)
+	}
+
+	limit, err := pageLimit(req.Limit)
+	if err != nil {
+		lg.Warn("house usecase: search error: bad limit", zap.Int("limit", req.Limit))
+		return domain.HouseSearchResponse{},
+			fmt.Errorf("house usecase: search error: %w", domain.ErrHouse_BadLimit)
+	}
+
+	filter := domain.HouseSearchFilter{
+		DeveloperID:      req.DeveloperID,
+		Developer:        req.Developer,
+		YearMin:          req.YearMin,
+		YearMax:          req.YearMax,
+		Address:          req.Address,
+		HasApprovedFlats: req.HasApprovedFlats,
+		Box:              req.Box,
+		Limit:            limit,
+	}
+
+	response, err := searchHousePage(ctx, u.houseRepo, &filter, req.Cursor, lg)
+	if err != nil {
+		lg.Warn("house usecase: search error", zap.Error(err))
+		return domain.HouseSearchResponse{}, fmt.Errorf("house usecase: search error: %w", err)
+	}
+
+	return response, nil
+}
+
+// searchHousePage loads one page of filter.Limit houses starting after the cursor.
+func searchHousePage(ctx context.Context, houseRepo domain.HouseRepo, filter *domain.HouseSearchFilter,
+	cursor string, lg *zap.Logger) (domain.HouseSearchResponse, error) {
+	limit := filter.Limit
+	pageFilter := *filter
+	pageFilter.Limit = limit + 1
+
+	if cursor != "" {
+		var after domain.HouseSearchCursor
+		err := pkg.DecodeCursor(cursor, &after)
+		if err != nil {
+			lg.Warn("house usecase: search house page error: bad cursor", zap.String("cursor", cursor))
+			return domain.HouseSearchResponse{}, domain.ErrHouse_BadCursor
+		}
+		pageFilter.After = &after
+	}
+
+	houses, err := houseRepo.Search(ctx, &pageFilter, lg)
+	if err != nil {
+		return domain.HouseSearchResponse{}, fmt.Errorf("search house page error: %v", err.Error())
+	}
+
+	var response domain.HouseSearchResponse
+	if len(houses) > limit {
+		houses = houses[:limit]
+		last := houses[limit-1].House
+		response.NextCursor, err = pkg.EncodeCursor(domain.HouseSearchCursor{
+			Date:    last.UpdateFlatDate,
+			HouseID: last.HouseID,
+		})
+		if err != nil {
+			return domain.HouseSearchResponse{}, fmt.Errorf("search house page error: encode cursor: %v", err.Error())
+		}
+	}
+
+	response.Houses = make([]domain.HouseListItemResponse, 0, len(houses))
+	for i := range houses {
+		response.Houses = append(response.Houses, domain.HouseListItemResponse{
+			CreateHouseResponse: toCreateHouseResponse(&houses[i].House),
+			Flats:               houses[i].Flats,
+			ApprovedFlats:       houses[i].ApprovedFlats,
+		})
+	}
+
+	return response, nil
+}
+
+func (u *HouseUsecase) SearchByAddress(ctx context.Context, req *domain.HouseAddressSearchRequest,
+	lg *zap.Logger) (domain.HouseAddressSearchResponse, error) {
+	lg.Info("house usecase: search by address")
+
+	if req == nil {
+		lg.Warn("house usecase: search by address error: bad request = nil")
+		return domain.HouseAddressSearchResponse{},
+			fmt.Errorf("house usecase: search by address error: %w", domain.ErrHouse_BadRequest)
+	}
+
+	query := NormalizeAddress(req.Query)
+	if query == "" || len([]rune(req.Query)) > domain.MaxAddressQueryLength {
+		lg.Warn("house usecase: search by address error: bad query", zap.String("q", req.Query))
+		return domain.HouseAddressSearchResponse{},
+			fmt.Errorf("house usecase: search by address error: %w", domain.ErrHouse_BadQuery)
+	}
+
+	limit, err := pageLimit(req.Limit)
+	if err != nil {
+		lg.Warn("house usecase: search by address error: bad limit", zap.Int("limit", req.Limit))
+		return domain.HouseAddressSearchResponse{},
+			fmt.Errorf("house usecase: search by address error: %w", domain.ErrHouse_BadLimit)
+	}
+
+	matches, err := u.houseRepo.SearchByAddress(ctx, &domain.HouseAddressFilter{
+		Query:     query,
+		Threshold: domain.AddressSimilarityThreshold,
+		Limit:     limit,
+	}, lg)
+	if err != nil {
+		lg.Warn("house usecase: search by address error", zap.Error(err))
+		return domain.HouseAddressSearchResponse{}, fmt.Errorf("house usecase: search by address error: %v", err.Error())
+	}
+
+	queryWords := strings.Fields(query)
+	response := domain.HouseAddressSearchResponse{Houses: make([]domain.HouseAddressMatchResponse, 0, len(matches))}
+	for i := range matches {
+		response.Houses = append(response.Houses, domain.HouseAddressMatchResponse{
+			CreateHouseResponse: toCreateHouseResponse(&matches[i].House),
+			Highlight:           highlightAddress(matches[i].House.Address, queryWords),
+			Similarity:          math.Round(matches[i].Similarity*1000) / 1000,
+		})
+	}
+
+	return response, nil
+}
+
+func (u *HouseUsecase) Nearby(ctx context.Context, req *domain.HouseNearbyRequest, lg *zap.Logger) (domain.HouseNearbyResponse, error) {
+	lg.Info("house usecase: nearby")
+
+	if req == nil {
+		lg.Warn("house usecase: nearby error: bad request = nil")
+		return domain.HouseNearbyResponse{},
+			fmt.Errorf("house usecase: nearby error: %w", domain.ErrHouse_BadRequest)
+	}
+
+	if !isLatitude(req.Latitude) || !isLongitude(req.Longitude) {
+		lg.Warn("house usecase: nearby error: bad location", zap.Float64("lat", req.Latitude),
+			zap.Float64("lon", req.Longitude))
+		return domain.HouseNearbyResponse{},
+			fmt.Errorf("house usecase: nearby error: %w", domain.ErrHouse_BadLocation)
+	}
+
+	if !(req.Radius > 0 && req.Radius <= domain.MaxNearbyRadius) {
+		lg.Warn("house usecase: nearby error: bad radius", zap.Float64("radius", req.Radius))
+		return domain.HouseNearbyResponse{},
+			fmt.Errorf("house usecase: nearby error: %w", domain.ErrHouse_BadRadius)
+	}
+
+	limit, err := pageLimit(req.Limit)
+	if err != nil {
+		lg.Warn("house usecase: nearby error: bad limit", zap.Int("limit", req.Limit))
+		return domain.HouseNearbyResponse{},
+			fmt.Errorf("house usecase: nearby error: %w", domain.ErrHouse_BadLimit)
+	}
+
+	houses, err := u.houseRepo.Nearby(ctx, &domain.HouseNearbyFilter{
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Radius:    req.Radius,
+		Limit:     limit,
+	}, lg)
+	if err != nil {
+		lg.Warn("house usecase: nearby error", zap.Error(err))
+		return domain.HouseNearbyResponse{}, fmt.Errorf("house usecase: nearby error: %v", err.Error())
+	}
+
+	response := domain.HouseNearbyResponse{Houses: make([]domain.HouseNearbyItemResponse, 0, len(houses))}
+	for i := range houses {
+		response.Houses = append(response.Houses, domain.HouseNearbyItemResponse{
+			HouseListItemResponse: domain.HouseListItemResponse{
+				CreateHouseResponse: toCreateHouseResponse(&houses[i].House),
+				Flats:               houses[i].Flats,
+				ApprovedFlats:       houses[i].ApprovedFlats,
+			},
+			Distance: math.Round(houses[i].Distance),
+		})
+	}
+
+	return response, nil
+}
+
+func parallelFlatFilter(flats []domain.Flat, lg *zap.Logger) domain.FlatsByHouseResponse {
+	flatsArr := make([]domain.SingleFlatResponse, len(flats))
+
+	lenOfPart := len(flats) / 3
 	var wg sync.WaitGroup
 
 	for i := 0; i < 3; i++ {
+		start := i * lenOfPart
+		end := start + lenOfPart
+		if i == 2 {
+			end = len(flats)
+		}
 		wg.Add(1)
 
-		go func(n int, part []domain.Flat, wg *sync.WaitGroup) {
+		go func(start int, end int, wg *sync.WaitGroup) {
 			defer wg.Done()
-			for j := 0; j < len(part); j++ {
-				singleFlat := domain.SingleFlatResponse{
-					ID:      parts[n][j].ID,
-					HouseID: parts[n][j].HouseID,
-					Price:   parts[n][j].Price,
-					Rooms:   parts[n][j].Rooms,
-					Status:  parts[n][j].Status,
-				}
-				mtx.Lock()
-				flatsArr = append(flatsArr, singleFlat)
-				mtx.Unlock()
+			for j := start; j < end; j++ {
+				flatsArr[j] = toSingleFlatResponse(&flats[j])
 			}
-		}(i, parts[i], &wg)
+		}(start, end, &wg)
 	}
 	wg.Wait()
-	return domain.FlatsByHouseResponse{flatsArr}
+	return domain.FlatsByHouseResponse{Flats: flatsArr}
 }
 
 func usualFlatFilter(flats []domain.Flat) domain.FlatsByHouseResponse {
 	var (
 		flatsArr []domain.SingleFlatResponse
 	)
-	for _, flat := range flats {
-		singleFlat := domain.SingleFlatResponse{
-			ID:      flat.ID,
-			HouseID: flat.HouseID,
-			Price:   flat.Price,
-			Rooms:   flat.Rooms,
-			Status:  flat.Status,
-		}
-		flatsArr = append(flatsArr, singleFlat)
+	for i := range flats {
+		flatsArr = append(flatsArr, toSingleFlatResponse(&flats[i]))
 	}
 
-	return domain.FlatsByHouseResponse{flatsArr}
+	return domain.FlatsByHouseResponse{Flats: flatsArr}
 }
 
-func (u *HouseUsecase) GetFlatsByHouseID(ctx context.Context, id int, status string, lg *zap.Logger) (domain.FlatsByHouseResponse, error) {
-	if id < 0 {
-		lg.Warn("house usecase: get flats by house id error: bad id", zap.Int("house_id", id))
+func houseCursorValue(flat *domain.Flat, sort string) int {
+	switch sort {
+	case domain.SortByPrice:
+		return flat.Price
+	case domain.SortByRooms:
+		return flat.Rooms
+	}
+	return 0
+}
+
+func (u *HouseUsecase) GetFlatsByHouseID(ctx context.Context, req *domain.FlatsByHouseRequest, status string, lg *zap.Logger) (domain.FlatsByHouseResponse, error) {
+	if req == nil {
+		lg.Warn("house usecase: get flats by house id error: bad request = nil")
+		return domain.FlatsByHouseResponse{},
+			fmt.Errorf("house usecase: get flats by house id error: %w", domain.ErrHouse_BadRequest)
+	}
+
+	if req.ID < 0 {
+		lg.Warn("house usecase: get flats by house id error: bad id", zap.Int("house_id", req.ID))
 		return domain.FlatsByHouseResponse{},
 			fmt.Errorf("house usecase: get flats by house id error: %w", domain.ErrHouse_BadID)
 	}
@@ -142,22 +530,122 @@ func (u *HouseUsecase) GetFlatsByHouseID(ctx context.Context, id int, status str
 			fmt.Errorf("house usecase: get flats by house id error: %w", domain.ErrFlat_BadStatus)
 	}
 
-	flats, err := u.houseRepo.GetFlatsByHouseID(ctx, id, status, lg)
+	if req.Rooms < 0 || req.PriceMin < 0 || req.PriceMax < 0 ||
+		(req.PriceMax > 0 && req.PriceMin > req.PriceMax) {
+		lg.Warn("house usecase: get flats by house id error: bad filter")
+		return domain.FlatsByHouseResponse{},
+			fmt.Errorf("house usecase: get flats by house id error: %w", domain.ErrFlat_BadFilter)
+	}
+
+	sort := req.Sort
+	if sort == "" {
+		sort = domain.SortByID
+	}
+	if sort != domain.SortByID && sort != domain.SortByPrice && sort != domain.SortByRooms {
+		lg.Warn("house usecase: get flats by house id error: bad sort", zap.String("sort", req.Sort))
+		return domain.FlatsByHouseResponse{},
+			fmt.Errorf("house usecase: get flats by house id error: %w", domain.ErrFlat_BadSort)
+	}
+
+	limit, err := pageLimit(req.Limit)
+	if err != nil {
+		lg.Warn("house usecase: get flats by house id error: bad limit", zap.Int("limit", req.Limit))
+		return domain.FlatsByHouseResponse{},
+			fmt.Errorf("house usecase: get flats by house id error: %w", err)
+	}
+
+	filter := domain.FlatsByHouseFilter{
+		Rooms:    req.Rooms,
+		PriceMin: req.PriceMin,
+		PriceMax: req.PriceMax,
+		Sort:     sort,
+		Limit:    limit + 1,
+	}
+
+	if req.Cursor != "" {
+		var cursor domain.FlatsByHouseCursor
+		err = pkg.DecodeCursor(req.Cursor, &cursor)
+		if err != nil || cursor.Sort != sort {
+			lg.Warn("house usecase: get flats by house id error: bad cursor", zap.String("cursor", req.Cursor))
+			return domain.FlatsByHouseResponse{},
+				fmt.Errorf("house usecase: get flats by house id error: %w", domain.ErrFlat_BadCursor)
+		}
+		filter.After = &cursor
+	}
+
+	flats, err := u.houseRepo.GetFlatsByHouseID(ctx, req.ID, status, &filter, lg)
 	if err != nil {
 		lg.Warn("house usecase: get flats by house id error", zap.Error(err))
 		return domain.FlatsByHouseResponse{}, fmt.Errorf("house usecase: get flats by house id error: %v", err.Error())
 	}
 
+	var nextCursor string
+	if len(flats) > limit {
+		flats = flats[:limit]
+		last := flats[limit-1]
+		nextCursor, err = pkg.EncodeCursor(domain.FlatsByHouseCursor{
+			Sort:   sort,
+			Value:  houseCursorValue(&last, sort),
+			FlatID: last.ID,
+		})
+		if err != nil {
+			lg.Warn("house usecase: get flats by house id error: encode cursor", zap.Error(err))
+			return domain.FlatsByHouseResponse{}, fmt.Errorf("house usecase: get flats by house id error: %v", err.Error())
+		}
+	}
+
 	var flatsResponse domain.FlatsByHouseResponse
 	if len(flats) < domain.FlatThreshhold {
 		flatsResponse = usualFlatFilter(flats)
 	} else {
 		flatsResponse = parallelFlatFilter(flats, lg)
 	}
+	flatsResponse.NextCursor = nextCursor
 
 	return flatsResponse, nil
 }
 
+// GetFlatsUpdateDate returns the time of the last change of the house flats; it validates cached flat lists.
+func (u *HouseUsecase) GetFlatsUpdateDate(ctx context.Context, id int, lg *zap.Logger) (time.Time, error) {
+	lg.Info("house usecase: get flats update date")
+
+	if id < 1 {
+		lg.Warn("house usecase: get flats update date error: bad id", zap.Int("house_id", id))
+		return time.Time{}, fmt.Errorf("house usecase: get flats update date error: %w", domain.ErrHouse_BadID)
+	}
+
+	house, err := u.houseRepo.GetByID(ctx, id, lg)
+	if err != nil {
+		lg.Warn("house usecase: get flats update date error", zap.Error(err))
+		return time.Time{}, fmt.Errorf("house usecase: get flats update date error: %w", err)
+	}
+
+	return house.UpdateFlatDate, nil
+}
+
+func (u *HouseUsecase) GetStats(ctx context.Context, id int, lg *zap.Logger) (domain.HouseStatsResponse, error) {
+	lg.Info("house usecase: get stats")
+
+	if id < 1 {
+		lg.Warn("house usecase: get stats error: bad id", zap.Int("house_id", id))
+		return domain.HouseStatsResponse{}, fmt.Errorf("house usecase: get stats error: %w", domain.ErrHouse_BadID)
+	}
+
+	_, err := u.houseRepo.GetByID(ctx, id, lg)
+	if err != nil {
+		lg.Warn("house usecase: get stats error", zap.Error(err))
+		return domain.HouseStatsResponse{}, fmt.Errorf("house usecase: get stats error: %w", err)
+	}
+
+	buckets, err := u.houseRepo.GetPriceBuckets(ctx, id, lg)
+	if err != nil {
+		lg.Warn("house usecase: get stats error", zap.Error(err))
+		return domain.HouseStatsResponse{}, fmt.Errorf("house usecase: get stats error: %v", err.Error())
+	}
+
+	return houseStats(id, buckets), nil
+}
+
 func (uc *HouseUsecase) SubscribeByID(ctx context.Context, id int, userID uuid.UUID, lg *zap.Logger) error {
 	lg.Info("house usecase: subscribe by id")
 
@@ -175,6 +663,114 @@ func (uc *HouseUsecase) SubscribeByID(ctx context.Context, id int, userID uuid.U
 	return nil
 }
 
+func (uc *HouseUsecase) UnsubscribeByID(ctx context.Context, id int, userID uuid.UUID, lg *zap.Logger) error {
+	lg.Info("house usecase: unsubscribe by id")
+
+	if id < 1 {
+		lg.Warn("house usecase: unsubscribe by id error: bad id", zap.Int("house_id", id))
+		return fmt.Errorf("house usecase: unsubscribe by id error: %w", domain.ErrHouse_BadID)
+	}
+
+	err := uc.houseRepo.UnsubscribeByID(ctx, id, userID, lg)
+	if err != nil {
+		lg.Warn("house usecase: unsubscribe by id error", zap.Error(err))
+		return fmt.Errorf("house usecase: unsubscribe by id error: %w", err)
+	}
+
+	return nil
+}
+
+// CheckUnsubscribeToken tells which house the link from a notification is for and whether the user
+// is still subscribed to it. It changes nothing: mail scanners and prefetchers open every link.
+func (uc *HouseUsecase) CheckUnsubscribeToken(ctx context.Context, token string, lg *zap.Logger) (domain.UnsubscribeResponse, error) {
+	lg.Info("house usecase: check unsubscribe token")
+
+	userID, houseID, err := pkg.ParseUnsubscribeToken(token)
+	if err != nil {
+		lg.Warn("house usecase: check unsubscribe token error", zap.Error(err))
+		return domain.UnsubscribeResponse{},
+			fmt.Errorf("house usecase: check unsubscribe token error: %w", domain.ErrSubscription_BadToken)
+	}
+
+	subscriptions, err := uc.houseRepo.GetSubscriptions(ctx, userID, lg)
+	if err != nil {
+		lg.Warn("house usecase: check unsubscribe token error", zap.Error(err))
+		return domain.UnsubscribeResponse{}, fmt.Errorf("house usecase: check unsubscribe token error: %v", err.Error())
+	}
+
+	response := domain.UnsubscribeResponse{HouseID: houseID}
+	for _, subscription := range subscriptions {
+		if subscription.HouseID == houseID {
+			response.Subscribed = true
+			break
+		}
+	}
+
+	return response, nil
+}
+
+// UnsubscribeByToken redeems the link from a notification. Following it again is not an error.
+func (uc *HouseUsecase) UnsubscribeByToken(ctx context.Context, token string, lg *zap.Logger) (domain.UnsubscribeResponse, error) {
+	lg.Info("house usecase: unsubscribe by token")
+
+	userID, houseID, err := pkg.ParseUnsubscribeToken(token)
+	if err != nil {
+		lg.Warn("house usecase: unsubscribe by token error", zap.Error(err))
+		return domain.UnsubscribeResponse{},
+			fmt.Errorf("house usecase: unsubscribe by token error: %w", domain.ErrSubscription_BadToken)
+	}
+
+	err = uc.houseRepo.UnsubscribeByID(ctx, houseID, userID, lg)
+	if err != nil && !errors.Is(err, domain.ErrSubscription_NotFound) {
+		lg.Warn("house usecase: unsubscribe by token error", zap.Error(err))
+		return domain.UnsubscribeResponse{}, fmt.Errorf("house usecase: unsubscribe by token error: %v", err.Error())
+	}
+
+	return domain.UnsubscribeResponse{HouseID: houseID}, nil
+}
+
+func (uc *HouseUsecase) GetSubscriptions(ctx context.Context, userID uuid.UUID, lg *zap.Logger) (domain.SubscriptionsResponse, error) {
+	lg.Info("house usecase: get subscriptions")
+
+	subscriptions, err := uc.houseRepo.GetSubscriptions(ctx, userID, lg)
+	if err != nil {
+		lg.Warn("house usecase: get subscriptions error", zap.Error(err))
+		return domain.SubscriptionsResponse{}, fmt.Errorf("house usecase: get subscriptions error: %v", err.Error())
+	}
+
+	response := domain.SubscriptionsResponse{
+		Subscriptions: make([]domain.SubscriptionResponse, 0, len(subscriptions)),
+	}
+	for _, subscription := range subscriptions {
+		response.Subscriptions = append(response.Subscriptions, domain.SubscriptionResponse{
+			HouseID:      subscription.HouseID,
+			Address:      subscription.Address,
+			SubscribedAt: subscription.SubscribeDate.Format(time.DateTime),
+		})
+	}
+
+	return response, nil
+}
+
+// unsubscribeLink is the one-click unsubscribe address for the recipient, empty when the recipient is unknown.
+func (uc *HouseUsecase) unsubscribeLink(notify *domain.Notify) string {
+	if uc.unsubscribeURL == "" || notify.UserID == uuid.Nil {
+		return ""
+	}
+
+	return uc.unsubscribeURL + "?token=" + pkg.GenerateUnsubscribeToken(notify.UserID, notify.HouseID)
+}
+
+// notifyMessage announces the new flat and adds the unsubscribe link when there is one.
+func notifyMessage(notify *domain.Notify, unsubscribeLink string) string {
+	msg := fmt.Sprintf("New flat with number %d in house %d!", notify.FlatID, notify.HouseID)
+	if unsubscribeLink == "" {
+		return msg
+	}
+
+	return msg + "\nUnsubscribe: " + unsubscribeLink
+}
+
 func (uc *HouseUsecase) Notifying(done chan bool, frequency time.Duration, timeout time.Duration, lg *zap.Logger) {
 	for {
 		select {
@@ -192,8 +788,8 @@ func (uc *HouseUsecase) Notifying(done chan bool, frequency time.Duration, timeo
 			}
 
 			for _, notify := range notifies {
-				msg := fmt.Sprintf("New flat with number %d in house %d!", notify.FlatID, notify.HouseID)
-				err = uc.notifySender.SendEmail(ctx, notify.UserMail, msg)
+				link := uc.unsubscribeLink(&notify)
+				err = uc.notifySender.SendEmail(ctx, notify.UserMail, notifyMessage(&notify, link), link)
 				if err != nil {
 					lg.Warn("house usecase: notifying error: send email error", zap.Error(err))
 					continue
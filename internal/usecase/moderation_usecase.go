@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"avito-test-task/internal/domain"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"time"
+)
+
+type ModerationUsecase struct {
+	moderationRepo domain.ModerationRepo
+	lease          time.Duration
+}
+
+func NewModerationUsecase(moderationRepo domain.ModerationRepo, lease time.Duration, done chan bool,
+	sweepFreq time.Duration, timeout time.Duration, lg *zap.Logger) *ModerationUsecase {
+	moderationUsecase := ModerationUsecase{
+		moderationRepo: moderationRepo,
+		lease:          lease,
+	}
+
+	go moderationUsecase.Sweeping(done, sweepFreq, timeout, lg)
+
+	return &moderationUsecase
+}
+
+func (u *ModerationUsecase) Claim(ctx context.Context, moderatorID uuid.UUID, lg *zap.Logger) (domain.CreateFlatResponse, error) {
+	lg.Info("moderation usecase: claim")
+
+	flat, err := u.moderationRepo.Claim(ctx, moderatorID, time.Now().Add(u.lease), lg)
+	if err != nil {
+		lg.Warn("moderation usecase: claim error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("moderation usecase: claim error: %w", err)
+	}
+
+	return toCreateFlatResponse(&flat), nil
+}
+
+func (u *ModerationUsecase) GetQueue(ctx context.Context, limit int, lg *zap.Logger) (domain.ModerationQueueResponse, error) {
+	lg.Info("moderation usecase: get queue")
+
+	pageSize, err := pageLimit(limit)
+	if err != nil {
+		lg.Warn("moderation usecase: get queue error: bad limit", zap.Int("limit", limit))
+		return domain.ModerationQueueResponse{},
+			fmt.Errorf("moderation usecase: get queue error: %w", domain.ErrModeration_BadLimit)
+	}
+
+	queue, err := u.moderationRepo.GetQueue(ctx, pageSize, lg)
+	if err != nil {
+		lg.Warn("moderation usecase: get queue error", zap.Error(err))
+		return domain.ModerationQueueResponse{}, fmt.Errorf("moderation usecase: get queue error: %v", err.Error())
+	}
+
+	response := domain.ModerationQueueResponse{
+		Depth:         queue.Depth,
+		Claimed:       queue.Claimed,
+		OldestAgeSec:  queue.OldestAgeSec,
+		AverageAgeSec: queue.AverageAgeSec,
+		Flats:         make([]domain.ModerationQueueItemResponse, 0, len(queue.Items)),
+	}
+	for _, item := range queue.Items {
+		response.Flats = append(response.Flats, domain.ModerationQueueItemResponse{
+			FlatID:      item.FlatID,
+			HouseID:     item.HouseID,
+			WaitingSec:  item.WaitingSec,
+			DuplicateOf: item.DuplicateOf,
+		})
+	}
+
+	return response, nil
+}
+
+func (u *ModerationUsecase) Renew(ctx context.Context, moderatorID uuid.UUID, req *domain.RenewClaimRequest,
+	lg *zap.Logger) (domain.CreateFlatResponse, error) {
+	lg.Info("moderation usecase: renew")
+
+	if req == nil {
+		lg.Warn("moderation usecase: renew error: bad request = nil")
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("moderation usecase: renew error: %w", domain.ErrFlat_BadRequest)
+	}
+
+	if req.ID < 1 {
+		lg.Warn("moderation usecase: renew error: bad flat id", zap.Int("flat_id", req.ID))
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("moderation usecase: renew error: %w", domain.ErrFlat_BadID)
+	}
+
+	if req.HouseID < 1 {
+		lg.Warn("moderation usecase: renew error: bad house id", zap.Int("house_id", req.HouseID))
+		return domain.CreateFlatResponse{},
+			fmt.Errorf("moderation usecase: renew error: %w", domain.ErrFlat_BadHouseID)
+	}
+
+	flat, err := u.moderationRepo.Renew(ctx, req.ID, req.HouseID, moderatorID, time.Now().Add(u.lease), lg)
+	if err != nil {
+		lg.Warn("moderation usecase: renew error", zap.Error(err))
+		return domain.CreateFlatResponse{}, fmt.Errorf("moderation usecase: renew error: %w", err)
+	}
+
+	return toCreateFlatResponse(&flat), nil
+}
+
+// Sweeping returns flats with an expired moderation lease to the queue.
+func (u *ModerationUsecase) Sweeping(done chan bool, frequency time.Duration, timeout time.Duration, lg *zap.Logger) {
+	if frequency <= 0 {
+		lg.Error("moderation usecase: sweeping goroutine not started: bad frequency", zap.Duration("frequency", frequency))
+		return
+	}
+
+	ticker := time.NewTicker(frequency)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			lg.Warn("moderation usecase: sweeping goroutine exited")
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			released, err := u.moderationRepo.ReleaseExpired(ctx, time.Now(), lg)
+			cancel()
+			if err != nil {
+				lg.Warn("moderation usecase: sweeping error", zap.Error(err))
+				continue
+			}
+			if released > 0 {
+				lg.Info("moderation usecase: released expired claims", zap.Int64("released", released))
+			}
+		}
+	}
+}
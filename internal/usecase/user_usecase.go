@@ -81,7 +81,7 @@ func (u *UserUsecase) Register(ctx context.Context, userReq *domain.RegisterUser
 		return domain.RegisterUserResponse{}, fmt.Errorf("user usecase: register error: %v", err.Error())
 	}
 
-	return domain.RegisterUserResponse{uuid}, nil
+	return domain.RegisterUserResponse{UserID: uuid}, nil
 }
 
 func (u *UserUsecase) Login(ctx context.Context, userReq *domain.LoginUserRequest, lg *zap.Logger) (domain.LoginUserResponse, error) {
@@ -111,7 +111,7 @@ func (u *UserUsecase) Login(ctx context.Context, userReq *domain.LoginUserReques
 		return domain.LoginUserResponse{}, fmt.Errorf("user usecase: login error: %v", err.Error())
 	}
 
-	return domain.LoginUserResponse{token}, nil
+	return domain.LoginUserResponse{Token: token}, nil
 }
 
 func (u *UserUsecase) DummyLogin(ctx context.Context, userType string, lg *zap.Logger) (domain.LoginUserResponse, error) {
@@ -150,5 +150,5 @@ func (u *UserUsecase) DummyLogin(ctx context.Context, userType string, lg *zap.L
 			fmt.Errorf("user usecase: dummy login error: %v", err.Error())
 	}
 
-	return domain.LoginUserResponse{token}, nil
+	return domain.LoginUserResponse{Token: token}, nil
 }
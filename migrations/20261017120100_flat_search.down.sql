@@ -0,0 +1,4 @@
+drop index if exists flats_approved_by_date;
+drop index if exists flats_approved_by_price;
+
+alter table flats drop column if exists create_flat_date;
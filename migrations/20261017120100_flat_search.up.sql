@@ -0,0 +1,10 @@
+alter table flats
+    add column create_flat_date timestamp without time zone not null default now();
+
+create index flats_approved_by_price
+    on flats (price, house_id, flat_id)
+    where status = 'approved';
+
+create index flats_approved_by_date
+    on flats (create_flat_date desc, house_id desc, flat_id desc)
+    where status = 'approved';
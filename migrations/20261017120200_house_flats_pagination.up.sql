@@ -0,0 +1,10 @@
+drop index if exists houses_id_on_flats;
+
+create index flats_house_by_id
+    on flats (house_id, flat_id);
+
+create index flats_house_by_price
+    on flats (house_id, price, flat_id);
+
+create index flats_house_by_rooms
+    on flats (house_id, rooms, flat_id);
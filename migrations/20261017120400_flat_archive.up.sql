@@ -0,0 +1,5 @@
+alter type flat_status add value if not exists 'archived';
+
+alter table flats
+    add column deleted_at timestamp without time zone,
+    add column archived_status flat_status;
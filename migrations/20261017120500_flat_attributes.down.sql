@@ -0,0 +1,8 @@
+alter table flats
+    drop column if exists total_area,
+    drop column if exists living_area,
+    drop column if exists floor,
+    drop column if exists floors,
+    drop column if exists description,
+    drop column if exists layout,
+    drop column if exists balcony;
@@ -0,0 +1,8 @@
+alter table flats
+    add column total_area numeric(8, 2) not null default 0,
+    add column living_area numeric(8, 2) not null default 0,
+    add column floor int not null default 0,
+    add column floors int not null default 0,
+    add column description text not null default '',
+    add column layout varchar(16) not null default '',
+    add column balcony varchar(16) not null default '';
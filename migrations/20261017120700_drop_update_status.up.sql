@@ -0,0 +1,2 @@
+-- status transitions are checked by the flat usecase now
+drop function if exists update_status(flat_status, int, int, uuid);
@@ -0,0 +1,3 @@
+drop index if exists flats_moderation_queue;
+
+alter table flats drop column if exists status_date;
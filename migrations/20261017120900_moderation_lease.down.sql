@@ -0,0 +1,3 @@
+drop index if exists flats_moderation_lease;
+
+alter table flats drop column if exists moderation_expires_at;
@@ -0,0 +1,13 @@
+create table moderation_decisions (
+    id serial primary key,
+    flat_id int not null,
+    house_id int not null,
+    moderator_id uuid references users(user_id),
+    decision flat_status not null,
+    reason_code varchar(64) not null default '',
+    comment text not null default '',
+    decision_date timestamp without time zone not null,
+    foreign key (flat_id, house_id) references flats(flat_id, house_id)
+);
+
+create index moderation_decisions_by_flat on moderation_decisions (house_id, flat_id, id);
@@ -0,0 +1,30 @@
+create table flat_status_history (
+    id bigserial primary key,
+    flat_id int not null,
+    house_id int not null,
+    from_status flat_status,
+    to_status flat_status not null,
+    actor_id uuid references users(user_id),
+    comment text not null default '',
+    change_date timestamp without time zone not null default now(),
+    foreign key (flat_id, house_id) references flats(flat_id, house_id)
+);
+
+create index flat_status_history_by_flat on flat_status_history (house_id, flat_id, id);
+
+create or replace function reject_status_history_change()
+    returns trigger as $$
+begin
+    raise exception 'flat status history is append-only';
+end;
+$$ language plpgsql;
+
+create trigger flat_status_history_append_only
+    before update or delete on flat_status_history
+    for each row
+execute function reject_status_history_change();
+
+-- flats created before the history existed start with their current status
+insert into flat_status_history(flat_id, house_id, from_status, to_status, actor_id, change_date)
+select flat_id, house_id, null, status, user_id, status_date from flats
+order by status_date, house_id, flat_id;
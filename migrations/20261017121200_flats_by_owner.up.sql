@@ -0,0 +1 @@
+create index if not exists flats_by_owner on flats (user_id, house_id, flat_id);
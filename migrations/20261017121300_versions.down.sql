@@ -0,0 +1,2 @@
+alter table houses drop column if exists version;
+alter table flats drop column if exists version;
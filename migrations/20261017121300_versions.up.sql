@@ -0,0 +1,2 @@
+alter table flats add column version int not null default 1;
+alter table houses add column version int not null default 1;
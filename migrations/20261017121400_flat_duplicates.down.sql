@@ -0,0 +1,2 @@
+drop index if exists flats_duplicate_lookup;
+alter table flats drop column if exists duplicate_of;
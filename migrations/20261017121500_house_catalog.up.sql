@@ -0,0 +1,7 @@
+update houses set update_flat_date = coalesce(create_house_date, now()) where update_flat_date is null;
+
+alter table houses
+    alter column update_flat_date set default now(),
+    alter column update_flat_date set not null;
+
+create index houses_by_update_flat_date on houses (update_flat_date desc, house_id desc);
@@ -0,0 +1,6 @@
+create extension if not exists pg_trgm;
+
+-- filled by the application on create and edit; existing houses: go run ./cmd/backfill -job addresses
+alter table houses add column normalized_address text;
+
+create index houses_by_normalized_address on houses using gin (normalized_address gin_trgm_ops);
@@ -0,0 +1,5 @@
+drop trigger if exists flats_count_approved on flats;
+
+drop function if exists count_approved_flats();
+
+drop table if exists house_price_counts;
@@ -0,0 +1,42 @@
+-- approved flats of every house grouped by rooms and price: house statistics are computed
+-- from these counters instead of scanning the flats
+create table house_price_counts (
+    house_id int not null references houses(house_id),
+    rooms int not null,
+    price int not null,
+    flats int not null check (flats >= 0),
+    primary key (house_id, rooms, price)
+);
+
+create or replace function count_approved_flats()
+    returns trigger as $$
+begin
+    if tg_op = 'UPDATE' and old.status = new.status and old.house_id = new.house_id
+        and old.rooms = new.rooms and old.price = new.price then
+        return null;
+    end if;
+
+    if tg_op in ('UPDATE', 'DELETE') and old.status = 'approved' then
+        update house_price_counts set flats = flats - 1
+        where house_id = old.house_id and rooms = old.rooms and price = old.price;
+    end if;
+
+    if tg_op in ('INSERT', 'UPDATE') and new.status = 'approved' then
+        insert into house_price_counts(house_id, rooms, price, flats)
+        values (new.house_id, new.rooms, new.price, 1)
+        on conflict (house_id, rooms, price) do update set flats = house_price_counts.flats + 1;
+    end if;
+
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger flats_count_approved
+    after insert or update of status, house_id, rooms, price or delete on flats
+    for each row
+execute function count_approved_flats();
+
+insert into house_price_counts(house_id, rooms, price, flats)
+select house_id, rooms, price, count(*) from flats
+where status = 'approved'
+group by house_id, rooms, price;
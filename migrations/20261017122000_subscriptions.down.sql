@@ -0,0 +1,31 @@
+create or replace function insert_flat_to_outbox()
+    returns trigger as $$
+declare
+    subscriber_mail text;
+    subscriber_mails text[];
+begin
+    select array_agg(u.mail)
+    into subscriber_mails
+    from subscribers s
+             join users u on u.user_id = s.user_id
+    where s.house_id = new.house_id;
+
+    if subscriber_mails is null then
+        return new;
+    end if;
+
+    foreach subscriber_mail in array subscriber_mails
+        loop
+            insert into new_flats_outbox(flat_id, house_id, mail, status)
+            values (new.flat_id, new.house_id, subscriber_mail, 'no send');
+        end loop;
+
+    return new;
+end;
+$$ language plpgsql;
+
+alter table new_flats_outbox drop column if exists user_id;
+
+drop index if exists subscribers_by_user;
+
+alter table subscribers drop column if exists subscribe_date;
@@ -0,0 +1,21 @@
+-- subscribers are notified by the application when a flat gets approved, not when it is inserted
+drop trigger if exists flat_create_trigger on flats;
+
+drop function if exists insert_flat_to_outbox();
+
+-- pending notifications about flats clients can't see would announce nothing
+delete from new_flats_outbox o
+where o.status = 'no send' and not exists (
+    select 1 from flats f
+    where f.flat_id = o.flat_id and f.house_id = o.house_id and f.status = 'approved'
+);
+
+delete from new_flats_outbox o
+where o.user_id is not null and exists (
+    select 1 from new_flats_outbox earlier
+    where earlier.flat_id = o.flat_id and earlier.house_id = o.house_id
+        and earlier.user_id = o.user_id and earlier.id < o.id
+);
+
+-- one notification per subscriber and flat, however many times the flat is approved
+create unique index new_flats_outbox_once on new_flats_outbox (flat_id, house_id, user_id);
@@ -1,8 +1,14 @@
 package pkg
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"strings"
 	"time"
 )
 
@@ -44,3 +50,44 @@ func ExtractPayloadFromToken(tokenString string, field string) (string, error) {
 
 	return "", err
 }
+
+// GenerateUnsubscribeToken signs the user and the house, so the link in a notification
+// cancels the subscription without a login. The token does not expire.
+func GenerateUnsubscribeToken(userID uuid.UUID, houseID int) string {
+	payload := make([]byte, 0, 24)
+	payload = append(payload, userID[:]...)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(houseID))
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(unsubscribeMAC(payload))
+}
+
+func ParseUnsubscribeToken(token string) (uuid.UUID, int, error) {
+	errBadToken := errors.New("bad unsubscribe token")
+
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, 0, errBadToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != 24 {
+		return uuid.Nil, 0, errBadToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, unsubscribeMAC(payload)) {
+		return uuid.Nil, 0, errBadToken
+	}
+
+	userID, err := uuid.FromBytes(payload[:16])
+	if err != nil {
+		return uuid.Nil, 0, errBadToken
+	}
+	return userID, int(binary.BigEndian.Uint64(payload[16:])), nil
+}
+
+// unsubscribeMAC keys the signature apart from the JWT one, so one kind of token can't pass for the other.
+func unsubscribeMAC(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte("unsubscribe:"+Key))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,6 @@
+drop index if exists flats_house_by_rooms;
+drop index if exists flats_house_by_price;
+drop index if exists flats_house_by_id;
+
+create index if not exists houses_id_on_flats
+    on flats (house_id);
@@ -0,0 +1,12 @@
+create table flat_edits (
+    id serial primary key,
+    flat_id int not null,
+    house_id int not null,
+    user_id uuid references users(user_id),
+    changes jsonb not null,
+    edit_date timestamp without time zone not null,
+    foreign key (flat_id, house_id) references flats(flat_id, house_id)
+);
+
+create index flat_edits_by_flat
+    on flat_edits (house_id, flat_id, id);
@@ -0,0 +1,8 @@
+update flats set status = archived_status
+    where deleted_at is not null and archived_status is not null;
+
+alter table flats
+    drop column if exists archived_status,
+    drop column if exists deleted_at;
+
+-- postgres can't remove a value from an enum, so 'archived' stays in flat_status
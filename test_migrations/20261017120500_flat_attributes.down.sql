@@ -0,0 +1,33 @@
+drop function if exists update_status(flat_status, int, int, uuid);
+
+create function update_status(new_status flat_status, new_flat_id int, new_house_id int, new_moderator_id uuid)
+    returns flat as $$
+declare
+    mod_id uuid;
+    f flat;
+begin
+    if new_status = 'on moderation' then
+        select flats.moderator_id into mod_id from flats
+        where flats.flat_id=new_flat_id and flats.house_id=new_house_id;
+
+        if mod_id != new_moderator_id then
+            raise exception 'flat already on moderation';
+        end if;
+    end if;
+
+        update flats set status=new_status, moderator_id=new_moderator_id
+            where flats.flat_id=new_flat_id and flats.house_id=new_house_id
+            returning flat_id, house_id, user_id, price, rooms, status, moderator_id
+        into f;
+    return f;
+end;
+$$ language plpgsql;
+
+alter table flats
+    drop column if exists total_area,
+    drop column if exists living_area,
+    drop column if exists floor,
+    drop column if exists floors,
+    drop column if exists description,
+    drop column if exists layout,
+    drop column if exists balcony;
@@ -0,0 +1,39 @@
+alter table flats
+    add column total_area numeric(8, 2) not null default 0,
+    add column living_area numeric(8, 2) not null default 0,
+    add column floor int not null default 0,
+    add column floors int not null default 0,
+    add column description text not null default '',
+    add column layout varchar(16) not null default '',
+    add column balcony varchar(16) not null default '';
+
+-- update_status returned the fixed composite type flat, which has no new columns
+drop function if exists update_status(flat_status, int, int, uuid);
+
+create function update_status(new_status flat_status, new_flat_id int, new_house_id int, new_moderator_id uuid)
+    returns setof flats as $$
+declare
+    mod_id uuid;
+begin
+    if new_status = 'on moderation' then
+        select flats.moderator_id into mod_id from flats
+        where flats.flat_id=new_flat_id and flats.house_id=new_house_id;
+
+        if mod_id != new_moderator_id then
+            raise exception 'flat already on moderation';
+        end if;
+    end if;
+
+    if new_status = 'approved' or new_status = 'declined' or new_status = 'created' then
+        return query
+            update flats set status=new_status, moderator_id=null
+                where flats.flat_id=new_flat_id and flats.house_id=new_house_id
+                returning *;
+    end if;
+
+    return query
+        update flats set status=new_status, moderator_id=new_moderator_id
+            where flats.flat_id=new_flat_id and flats.house_id=new_house_id
+        returning *;
+end;
+$$ language plpgsql;
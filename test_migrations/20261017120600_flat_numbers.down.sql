@@ -0,0 +1 @@
+alter table houses drop column if exists last_flat_id;
@@ -0,0 +1,4 @@
+alter table houses add column last_flat_id int not null default 0;
+
+update houses h set last_flat_id = coalesce(
+    (select max(f.flat_id) from flats f where f.house_id = h.house_id), 0);
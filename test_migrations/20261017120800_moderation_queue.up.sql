@@ -0,0 +1,5 @@
+alter table flats add column status_date timestamp without time zone not null default now();
+
+update flats set status_date = create_flat_date;
+
+create index flats_moderation_queue on flats (status_date, house_id, flat_id) where status = 'created';
@@ -0,0 +1,7 @@
+alter table flats add column moderation_expires_at timestamp without time zone;
+
+-- claims taken before leases existed get a fresh default lease
+update flats set moderation_expires_at = now() + interval '15 minutes'
+    where status = 'on moderation';
+
+create index flats_moderation_lease on flats (moderation_expires_at) where status = 'on moderation';
@@ -0,0 +1,3 @@
+drop trigger if exists flat_status_history_append_only on flat_status_history;
+drop function if exists reject_status_history_change();
+drop table if exists flat_status_history;
@@ -0,0 +1 @@
+drop index if exists flats_by_owner;
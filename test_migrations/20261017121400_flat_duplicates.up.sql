@@ -0,0 +1,5 @@
+alter table flats
+    add column duplicate_of int,
+    add foreign key (duplicate_of, house_id) references flats(flat_id, house_id);
+
+create index flats_duplicate_lookup on flats (house_id, user_id, rooms, price);
@@ -0,0 +1,5 @@
+drop index if exists houses_by_update_flat_date;
+
+alter table houses
+    alter column update_flat_date drop not null,
+    alter column update_flat_date drop default;
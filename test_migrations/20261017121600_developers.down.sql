@@ -0,0 +1,11 @@
+alter table houses add column developer text;
+
+update houses h set developer = d.name
+from developers d
+where d.developer_id = h.developer_id;
+
+drop index if exists houses_by_developer;
+alter table houses drop column if exists developer_id;
+
+drop table if exists developers;
+drop function if exists normalize_developer_name(text);
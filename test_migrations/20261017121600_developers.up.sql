@@ -0,0 +1,47 @@
+-- "ПИК", "PIK" and "p.i.k." all normalize to "pik"
+create function normalize_developer_name(name text)
+    returns text as $$
+    select lower(regexp_replace(
+        regexp_replace(regexp_replace(regexp_replace(regexp_replace(
+        regexp_replace(regexp_replace(regexp_replace(
+            translate(name,
+                'абвгдеёзийклмнопрстуфхыэАБВГДЕЁЗИЙКЛМНОПРСТУФХЫЭъьЪЬ',
+                'abvgdeezijklmnoprstufhyeABVGDEEZIJKLMNOPRSTUFHYE'),
+            '[жЖ]', 'zh', 'g'), '[цЦ]', 'ts', 'g'), '[чЧ]', 'ch', 'g'), '[шШ]', 'sh', 'g'),
+            '[щЩ]', 'sch', 'g'), '[юЮ]', 'yu', 'g'), '[яЯ]', 'ya', 'g'),
+        '[^a-zA-Z0-9]+', '', 'g'))
+$$ language sql immutable;
+
+create table developers (
+    developer_id serial primary key,
+    name text not null,
+    normalized_name text generated always as (normalize_developer_name(name)) stored,
+    create_developer_date timestamp without time zone not null default now(),
+    constraint developers_name_not_blank check (normalized_name <> '')
+);
+
+create unique index developers_by_normalized_name on developers (normalized_name);
+create index developers_by_name on developers (name, developer_id);
+
+-- the most used spelling of every developer becomes its name
+insert into developers(name)
+select distinct on (normalized_name) developer
+from (
+    select developer, normalize_developer_name(developer) as normalized_name,
+        count(*) as houses, min(house_id) as first_house
+    from houses
+    where developer is not null
+    group by developer
+) spellings
+where normalized_name <> ''
+order by normalized_name, houses desc, first_house;
+
+alter table houses add column developer_id int references developers(developer_id);
+
+update houses h set developer_id = d.developer_id
+from developers d
+where d.normalized_name = normalize_developer_name(h.developer);
+
+create index houses_by_developer on houses (developer_id, update_flat_date desc, house_id desc);
+
+alter table houses drop column developer;
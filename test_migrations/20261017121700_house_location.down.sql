@@ -0,0 +1,12 @@
+drop index if exists houses_by_location;
+drop index if exists houses_by_earth_location;
+
+alter table houses
+    drop constraint if exists houses_longitude_range,
+    drop constraint if exists houses_latitude_range,
+    drop constraint if exists houses_location_pair,
+    drop column if exists longitude,
+    drop column if exists latitude;
+
+drop extension if exists earthdistance;
+drop extension if exists cube;
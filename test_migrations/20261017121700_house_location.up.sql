@@ -0,0 +1,18 @@
+create extension if not exists cube;
+create extension if not exists earthdistance;
+
+alter table houses
+    add column latitude double precision,
+    add column longitude double precision,
+    add constraint houses_location_pair check ((latitude is null) = (longitude is null)),
+    add constraint houses_latitude_range check (latitude between -90 and 90),
+    add constraint houses_longitude_range check (longitude between -180 and 180);
+
+-- radius search: earth_box(...) @> ll_to_earth(latitude, longitude)
+create index houses_by_earth_location on houses
+    using gist (ll_to_earth(latitude, longitude))
+    where latitude is not null;
+
+-- map view: latitude/longitude ranges
+create index houses_by_location on houses (latitude, longitude)
+    where latitude is not null;
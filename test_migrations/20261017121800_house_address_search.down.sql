@@ -0,0 +1,5 @@
+drop index if exists houses_by_normalized_address;
+
+alter table houses drop column if exists normalized_address;
+
+drop extension if exists pg_trgm;
@@ -0,0 +1,27 @@
+alter table subscribers
+    add column subscribe_date timestamp without time zone not null default now();
+
+create index subscribers_by_user on subscribers (user_id, subscribe_date desc);
+
+-- the recipient is kept by id so a notification can carry its unsubscribe link
+alter table new_flats_outbox add column user_id uuid references users(user_id);
+
+update new_flats_outbox o set user_id = (
+    select s.user_id from subscribers s
+    join users u on u.user_id = s.user_id
+    where s.house_id = o.house_id and u.mail = o.mail
+    limit 1
+);
+
+create or replace function insert_flat_to_outbox()
+    returns trigger as $$
+begin
+    insert into new_flats_outbox(flat_id, house_id, user_id, mail, status)
+    select new.flat_id, new.house_id, u.user_id, u.mail, 'no send'
+    from subscribers s
+    join users u on u.user_id = s.user_id
+    where s.house_id = new.house_id;
+
+    return new;
+end;
+$$ language plpgsql;
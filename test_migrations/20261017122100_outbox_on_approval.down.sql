@@ -0,0 +1,19 @@
+drop index if exists new_flats_outbox_once;
+
+create or replace function insert_flat_to_outbox()
+    returns trigger as $$
+begin
+    insert into new_flats_outbox(flat_id, house_id, user_id, mail, status)
+    select new.flat_id, new.house_id, u.user_id, u.mail, 'no send'
+    from subscribers s
+    join users u on u.user_id = s.user_id
+    where s.house_id = new.house_id;
+
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger flat_create_trigger
+    after insert on flats
+    for each row
+execute function insert_flat_to_outbox();
@@ -0,0 +1,23 @@
+package tests
+
+import (
+	"avito-test-task/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	cases := map[string]string{
+		"ул. Тверская, д.7":                 "улица тверская дом 7",
+		"улица  Тверская дом 7":             "улица тверская дом 7",
+		"Ленинский пр-т, 32А, корп. 2":      "ленинский проспект 32а корпус 2",
+		"г. Москва, Пролетарский пр-д, 7/1": "город москва пролетарский проезд 7/1",
+		"Новослободская ул., стр. 3":        "новослободская улица строение 3",
+		"Зелёный пр-кт, 5 -":                "зеленый проспект 5",
+		"  ":                                "",
+	}
+
+	for address, expected := range cases {
+		assert.Equal(t, expected, usecase.NormalizeAddress(address), address)
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"avito-test-task/internal/domain"
+	"avito-test-task/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testAutoModerationRules = []domain.AutoModerationRule{
+	{Name: "no_prepayment", Kind: domain.BannedWordsRule, Outcome: domain.AutoDeclineOutcome,
+		ReasonCode: "other", Words: []string{"Предоплата"}},
+	{Name: "rooms_sanity", Kind: domain.RoomsPriceRule, Outcome: domain.AutoDeclineOutcome,
+		ReasonCode: "wrong_price", MaxRooms: 10, MinPricePerRoom: 100},
+	{Name: "price_outlier", Kind: domain.PriceOutlierRule, Outcome: domain.AutoFlagOutcome,
+		MaxDeviation: 0.5, MinFlats: 3},
+	{Name: "trusted", Kind: domain.TrustedOwnerRule, Outcome: domain.AutoApproveOutcome,
+		Owners: []uuid.UUID{uuid.MustParse("019126ee-2b7d-758e-bb22-fe2e45b2db22")}},
+}
+
+func TestAutoModeratorRules(t *testing.T) {
+	moderator, err := usecase.NewAutoModerator(testAutoModerationRules, testDeclineReasons)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.True(t, moderator.NeedsPriceStats())
+
+	trustedID := uuid.MustParse("019126ee-2b7d-758e-bb22-fe2e45b2db22")
+	stats := domain.HousePriceStats{MedianPrice: 1000, Flats: 5}
+
+	tests := []struct {
+		name    string
+		flat    domain.Flat
+		stats   domain.HousePriceStats
+		rule    string
+		outcome string
+	}{
+		{"banned word", domain.Flat{UserID: trustedID, Price: 1000, Rooms: 1, Description: "нужна предоплата"},
+			stats, "no_prepayment", domain.AutoDeclineOutcome},
+		{"too many rooms", domain.Flat{Price: 5000, Rooms: 11}, stats, "rooms_sanity", domain.AutoDeclineOutcome},
+		{"too cheap per room", domain.Flat{Price: 150, Rooms: 2}, stats, "rooms_sanity", domain.AutoDeclineOutcome},
+		{"price outlier", domain.Flat{Price: 2000, Rooms: 1}, stats, "price_outlier", domain.AutoFlagOutcome},
+		{"too few flats for median", domain.Flat{UserID: trustedID, Price: 2000, Rooms: 1},
+			domain.HousePriceStats{MedianPrice: 1000, Flats: 2}, "trusted", domain.AutoApproveOutcome},
+		{"trusted owner", domain.Flat{UserID: trustedID, Price: 1200, Rooms: 1}, stats, "trusted", domain.AutoApproveOutcome},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := moderator.Evaluate(&tt.flat, tt.stats)
+			if assert.NotNil(t, verdict) {
+				assert.Equal(t, tt.rule, verdict.Rule)
+				assert.Equal(t, tt.outcome, verdict.Outcome)
+				assert.Contains(t, verdict.Comment, tt.rule)
+			}
+		})
+	}
+
+	assert.Nil(t, moderator.Evaluate(&domain.Flat{UserID: uuid.New(), Price: 1100, Rooms: 1}, stats))
+}
+
+func TestAutoModeratorBadRules(t *testing.T) {
+	_, err := usecase.NewAutoModerator([]domain.AutoModerationRule{
+		{Name: "unknown", Kind: "magic", Outcome: domain.AutoFlagOutcome}}, testDeclineReasons)
+	assert.ErrorIs(t, err, domain.ErrAutoModeration_BadKind)
+
+	_, err = usecase.NewAutoModerator([]domain.AutoModerationRule{
+		{Name: "bad outcome", Kind: domain.TrustedOwnerRule, Outcome: "publish"}}, testDeclineReasons)
+	assert.ErrorIs(t, err, domain.ErrAutoModeration_BadOutcome)
+
+	_, err = usecase.NewAutoModerator([]domain.AutoModerationRule{
+		{Name: "no reason", Kind: domain.BannedWordsRule, Outcome: domain.AutoDeclineOutcome}}, testDeclineReasons)
+	assert.ErrorIs(t, err, domain.ErrAutoModeration_BadReason)
+}
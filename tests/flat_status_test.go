@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"avito-test-task/internal/domain"
+	"avito-test-task/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFlatTransitionModeration(t *testing.T) {
+	assert.NoError(t, usecase.CheckFlatTransition(domain.CreatedStatus, domain.ModeratingStatus, usecase.ModeratorActor))
+	assert.NoError(t, usecase.CheckFlatTransition(domain.ModeratingStatus, domain.ApprovedStatus, usecase.ModeratorActor))
+	assert.NoError(t, usecase.CheckFlatTransition(domain.ModeratingStatus, domain.DeclinedStatus, usecase.ModeratorActor))
+	assert.NoError(t, usecase.CheckFlatTransition(domain.ModeratingStatus, domain.CreatedStatus, usecase.ModeratorActor))
+}
+
+func TestFlatTransitionResubmit(t *testing.T) {
+	assert.NoError(t, usecase.CheckFlatTransition(domain.ApprovedStatus, domain.CreatedStatus, usecase.OwnerActor))
+	assert.NoError(t, usecase.CheckFlatTransition(domain.DeclinedStatus, domain.CreatedStatus, usecase.OwnerActor))
+
+	err := usecase.CheckFlatTransition(domain.ApprovedStatus, domain.CreatedStatus, usecase.ModeratorActor)
+	assert.ErrorIs(t, err, domain.ErrFlat_BadTransition)
+}
+
+func TestFlatTransitionIllegal(t *testing.T) {
+	cases := []struct {
+		from  string
+		to    string
+		actor string
+	}{
+		{domain.CreatedStatus, domain.ApprovedStatus, usecase.ModeratorActor},
+		{domain.ApprovedStatus, domain.DeclinedStatus, usecase.ModeratorActor},
+		{domain.DeclinedStatus, domain.ModeratingStatus, usecase.ModeratorActor},
+		{domain.CreatedStatus, domain.ModeratingStatus, usecase.OwnerActor},
+		{domain.ModeratingStatus, domain.ApprovedStatus, usecase.OwnerActor},
+		{domain.ArchivedStatus, domain.ArchivedStatus, usecase.OwnerActor},
+		{domain.CreatedStatus, domain.AnyStatus, usecase.ModeratorActor},
+	}
+
+	for _, c := range cases {
+		err := usecase.CheckFlatTransition(c.from, c.to, c.actor)
+		assert.ErrorIs(t, err, domain.ErrFlat_BadTransition, "%s -> %s by %s", c.from, c.to, c.actor)
+
+		var transitionErr *domain.FlatTransitionError
+		if assert.ErrorAs(t, err, &transitionErr) {
+			assert.Equal(t, c.from, transitionErr.From)
+			assert.Equal(t, c.to, transitionErr.To)
+		}
+	}
+}
+
+func TestModeratorTransitionOtherModerator(t *testing.T) {
+	holder := uuid.New()
+	flat := domain.Flat{Status: domain.ModeratingStatus, ModeratorID: holder}
+
+	assert.NoError(t, usecase.CheckModeratorTransition(&flat, domain.ApprovedStatus, holder))
+
+	err := usecase.CheckModeratorTransition(&flat, domain.ApprovedStatus, uuid.New())
+	assert.ErrorIs(t, err, domain.ErrFlat_OnModeration)
+}
@@ -7,6 +7,7 @@ import (
 	"avito-test-task/internal/usecase"
 	"avito-test-task/pkg"
 	"context"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -36,7 +37,7 @@ func initHouseEnv() (domain.HouseUsecase, *zap.Logger, *pgxpool.Pool) {
 
 	lg, _ := pkg.CreateLogger("../log.log", "prod")
 	houseRepo := repo.NewPostgresHouseRepo(pool, retryAdapter)
-	houseUsecase := usecase.NewHouseUsecase(houseRepo, notifySender, notifyRepo,
+	houseUsecase := usecase.NewHouseUsecase(houseRepo, notifySender, notifyRepo, "http://localhost/unsubscribe",
 		done, time.Second, time.Second, lg)
 
 	return houseUsecase, lg, pool
@@ -51,10 +52,10 @@ func TestCreateHouseNormal(t *testing.T) {
 	defer cancel()
 
 	req := domain.CreateHouseRequest{
-		HomeID:    0,
-		Address:   "address",
-		Year:      1000,
-		Developer: "dev",
+		HomeID:      0,
+		Address:     "address",
+		Year:        1000,
+		DeveloperID: 1,
 	}
 
 	resp, err := houseUsecase.Create(ctx, &req, lg)
@@ -63,17 +64,19 @@ func TestCreateHouseNormal(t *testing.T) {
 	}
 
 	expected := domain.CreateHouseResponse{
-		HomeID:    3,
-		Address:   "address",
-		Year:      1000,
-		Developer: "dev",
-		CreatedAt: "",
-		UpdateAt:  "",
+		HomeID:      3,
+		Address:     "address",
+		Year:        1000,
+		DeveloperID: 1,
+		Developer:   "dev",
+		CreatedAt:   "",
+		UpdateAt:    "",
 	}
 	t.Log(resp)
 
 	if expected.HomeID != resp.HomeID || expected.Address != resp.Address ||
-		expected.Year != resp.Year || expected.Developer != resp.Developer {
+		expected.Year != resp.Year || expected.DeveloperID != resp.DeveloperID ||
+		expected.Developer != resp.Developer {
 		assert.Fail(t, "not same")
 	}
 }
@@ -87,10 +90,10 @@ func TestCreateBadHouseID(t *testing.T) {
 	defer cancel()
 
 	req := domain.CreateHouseRequest{
-		HomeID:    0,
-		Address:   "address",
-		Year:      -1000,
-		Developer: "dev",
+		HomeID:      0,
+		Address:     "address",
+		Year:        -1000,
+		DeveloperID: 1,
 	}
 
 	_, err := houseUsecase.Create(ctx, &req, lg)
@@ -105,7 +108,7 @@ func TestGetFlatsByID(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	resp, err := houseUsecase.GetFlatsByHouseID(ctx, 1, "created", lg)
+	resp, err := houseUsecase.GetFlatsByHouseID(ctx, &domain.FlatsByHouseRequest{ID: 1}, "created", lg)
 	if err != nil {
 		assert.Fail(t, err.Error())
 	}
@@ -116,6 +119,7 @@ func TestGetFlatsByID(t *testing.T) {
 		Price:   100,
 		Rooms:   2,
 		Status:  "created",
+		Version: 1,
 	}
 	flats := make([]domain.SingleFlatResponse, 0)
 	flats = append(flats, expectedFlat)
@@ -132,7 +136,7 @@ func TestGetFlatsByIDNullFlats(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	resp, err := houseUsecase.GetFlatsByHouseID(ctx, 2, "created", lg)
+	resp, err := houseUsecase.GetFlatsByHouseID(ctx, &domain.FlatsByHouseRequest{ID: 2}, "created", lg)
 	if err != nil {
 		assert.Fail(t, err.Error())
 	}
@@ -140,3 +144,389 @@ func TestGetFlatsByIDNullFlats(t *testing.T) {
 	expected := domain.FlatsByHouseResponse{Flats: nil}
 	assert.Equal(t, expected, resp)
 }
+
+func TestGetFlatsByIDPaginatedByPrice(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	initDB("")
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := pool.Exec(ctx, `insert into flats(flat_id, house_id, user_id, price, rooms, status)
+		values (1, 2, '019126ee-2b7d-758e-bb22-fe2e45b2db22', 300, 1, 'approved'),
+		       (2, 2, '019126ee-2b7d-758e-bb22-fe2e45b2db22', 100, 2, 'approved'),
+		       (3, 2, '019126ee-2b7d-758e-bb22-fe2e45b2db22', 200, 3, 'approved')`)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+
+	req := domain.FlatsByHouseRequest{ID: 2, Sort: domain.SortByPrice, Limit: 2}
+	firstPage, err := houseUsecase.GetFlatsByHouseID(ctx, &req, domain.ApprovedStatus, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Equal(t, []domain.SingleFlatResponse{
+		{ID: 2, HouseID: 2, Price: 100, Rooms: 2, Status: domain.ApprovedStatus},
+		{ID: 3, HouseID: 2, Price: 200, Rooms: 3, Status: domain.ApprovedStatus},
+	}, firstPage.Flats)
+	assert.NotEmpty(t, firstPage.NextCursor)
+
+	req.Cursor = firstPage.NextCursor
+	secondPage, err := houseUsecase.GetFlatsByHouseID(ctx, &req, domain.ApprovedStatus, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Equal(t, []domain.SingleFlatResponse{
+		{ID: 1, HouseID: 2, Price: 300, Rooms: 1, Status: domain.ApprovedStatus},
+	}, secondPage.Flats)
+	assert.Empty(t, secondPage.NextCursor)
+}
+
+func TestSearchHouses(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	initDB("")
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := pool.Exec(ctx, "update flats set status='approved' where flat_id=10 and house_id=1")
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+
+	firstPage, err := houseUsecase.Search(ctx, &domain.HouseSearchRequest{Limit: 1}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	if assert.Len(t, firstPage.Houses, 1) {
+		assert.Equal(t, 2, firstPage.Houses[0].HomeID)
+	}
+	assert.NotEmpty(t, firstPage.NextCursor)
+
+	secondPage, err := houseUsecase.Search(ctx, &domain.HouseSearchRequest{Limit: 1, Cursor: firstPage.NextCursor}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	if assert.Len(t, secondPage.Houses, 1) {
+		assert.Equal(t, 1, secondPage.Houses[0].HomeID)
+		assert.Equal(t, 1, secondPage.Houses[0].Flats)
+		assert.Equal(t, 1, secondPage.Houses[0].ApprovedFlats)
+	}
+	assert.Empty(t, secondPage.NextCursor)
+
+	withFlats, err := houseUsecase.Search(ctx, &domain.HouseSearchRequest{HasApprovedFlats: true, Address: "addr"}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	if assert.Len(t, withFlats.Houses, 1) {
+		assert.Equal(t, 1, withFlats.Houses[0].HomeID)
+	}
+
+	literal, err := houseUsecase.Search(ctx, &domain.HouseSearchRequest{Address: "addr%"}, lg)
+	assert.NoError(t, err)
+	assert.Empty(t, literal.Houses)
+
+	byDeveloper, err := houseUsecase.Search(ctx, &domain.HouseSearchRequest{DeveloperID: 1}, lg)
+	assert.NoError(t, err)
+	assert.Len(t, byDeveloper.Houses, 2)
+
+	otherDeveloper, err := houseUsecase.Search(ctx, &domain.HouseSearchRequest{DeveloperID: 2}, lg)
+	assert.NoError(t, err)
+	assert.Empty(t, otherDeveloper.Houses)
+
+	_, err = houseUsecase.Search(ctx, &domain.HouseSearchRequest{YearMin: 2023, YearMax: 2020}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_BadFilter)
+
+	_, err = houseUsecase.Search(ctx, &domain.HouseSearchRequest{DeveloperID: -1}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_BadFilter)
+}
+
+func TestEditHouse(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	initDB("")
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	address := "new address"
+	resp, err := houseUsecase.Edit(ctx, &domain.EditHouseRequest{ID: 2, Address: &address, Version: 1}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Equal(t, "new address", resp.Address)
+	assert.Equal(t, "dev", resp.Developer)
+	assert.Equal(t, 2022, resp.Year)
+	assert.Equal(t, 2, resp.Version)
+
+	_, err = houseUsecase.Edit(ctx, &domain.EditHouseRequest{ID: 2, Address: &address, Version: 1}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_VersionMismatch)
+
+	_, err = houseUsecase.Edit(ctx, &domain.EditHouseRequest{ID: 2}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_BadEdit)
+
+	_, err = houseUsecase.Edit(ctx, &domain.EditHouseRequest{ID: 100, Address: &address}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_NotFound)
+
+	developerID := 100
+	_, err = houseUsecase.Edit(ctx, &domain.EditHouseRequest{ID: 2, DeveloperID: &developerID}, lg)
+	assert.ErrorIs(t, err, domain.ErrDeveloper_NotFound)
+}
+
+func TestDeleteHouse(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	initDB("")
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := houseUsecase.SubscribeByID(ctx, 2, uuid.MustParse("019126ee-2b7d-758e-bb22-fe2e45b2db22"), lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+
+	err = houseUsecase.Delete(ctx, &domain.DeleteHouseRequest{ID: 1}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_HasFlats)
+
+	err = houseUsecase.Delete(ctx, &domain.DeleteHouseRequest{ID: 2}, lg)
+	assert.NoError(t, err)
+
+	var subscribers int
+	err = pool.QueryRow(ctx, "select count(*) from subscribers where house_id=2").Scan(&subscribers)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, subscribers)
+
+	err = houseUsecase.Delete(ctx, &domain.DeleteHouseRequest{ID: 2}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_NotFound)
+}
+
+func TestNearbyHouses(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	initDB("")
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	locations := [][2]float64{
+		{55.7539, 37.6208}, // Red Square
+		{55.7601, 37.6186}, // Bolshoi Theatre, about 700 m away
+		{59.9398, 30.3146}, // Saint Petersburg
+	}
+	for i := range locations {
+		_, err := houseUsecase.Create(ctx, &domain.CreateHouseRequest{
+			Address:     "address",
+			Year:        2000,
+			DeveloperID: 1,
+			Latitude:    &locations[i][0],
+			Longitude:   &locations[i][1],
+		}, lg)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+	}
+
+	resp, err := houseUsecase.Nearby(ctx, &domain.HouseNearbyRequest{
+		Latitude: 55.7539, Longitude: 37.6208, Radius: 2000}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	if assert.Len(t, resp.Houses, 2) {
+		assert.Equal(t, 3, resp.Houses[0].HomeID)
+		assert.Equal(t, float64(0), resp.Houses[0].Distance)
+		assert.Equal(t, 4, resp.Houses[1].HomeID)
+		assert.InDelta(t, 700, resp.Houses[1].Distance, 100)
+	}
+
+	box, err := houseUsecase.Search(ctx, &domain.HouseSearchRequest{
+		Box: &domain.GeoBox{MinLat: 55, MaxLat: 56, MinLon: 37, MaxLon: 38}}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Len(t, box.Houses, 2)
+
+	_, err = houseUsecase.Nearby(ctx, &domain.HouseNearbyRequest{Latitude: 100, Longitude: 37, Radius: 1000}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_BadLocation)
+
+	_, err = houseUsecase.Nearby(ctx, &domain.HouseNearbyRequest{Latitude: 55, Longitude: 37}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_BadRadius)
+
+	_, err = houseUsecase.Create(ctx, &domain.CreateHouseRequest{
+		Address: "address", DeveloperID: 1, Latitude: &locations[0][0]}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_BadLocation)
+}
+
+func TestSearchHousesByAddress(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	initDB("")
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, address := range []string{"ул. Тверская, д. 7", "Ленинский пр-т, 32"} {
+		_, err := houseUsecase.Create(ctx, &domain.CreateHouseRequest{Address: address, Year: 2000, DeveloperID: 1}, lg)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+	}
+
+	resp, err := houseUsecase.SearchByAddress(ctx, &domain.HouseAddressSearchRequest{Query: "Тверская улица 7"}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	if assert.NotEmpty(t, resp.Houses) {
+		assert.Equal(t, 3, resp.Houses[0].HomeID)
+		assert.Equal(t, "<mark>ул</mark>. <mark>Тверская</mark>, д. <mark>7</mark>", resp.Houses[0].Highlight)
+	}
+
+	typo, err := houseUsecase.SearchByAddress(ctx, &domain.HouseAddressSearchRequest{Query: "тверскя"}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	if assert.NotEmpty(t, typo.Houses) {
+		assert.Equal(t, 3, typo.Houses[0].HomeID)
+	}
+
+	// words with inner hyphens are marked as a whole, like they are matched
+	hyphen, err := houseUsecase.SearchByAddress(ctx, &domain.HouseAddressSearchRequest{Query: "ленинский пр-т 32"}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	if assert.NotEmpty(t, hyphen.Houses) {
+		assert.Equal(t, 4, hyphen.Houses[0].HomeID)
+		assert.Equal(t, "<mark>Ленинский</mark> <mark>пр-т</mark>, <mark>32</mark>", hyphen.Houses[0].Highlight)
+	}
+
+	// houses from the test data were stored without a normalized address
+	before, err := houseUsecase.SearchByAddress(ctx, &domain.HouseAddressSearchRequest{Query: "address"}, lg)
+	assert.NoError(t, err)
+	assert.Empty(t, before.Houses)
+
+	updated, err := houseUsecase.(*usecase.HouseUsecase).BackfillAddresses(ctx, 1, lg)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, updated)
+
+	after, err := houseUsecase.SearchByAddress(ctx, &domain.HouseAddressSearchRequest{Query: "address"}, lg)
+	assert.NoError(t, err)
+	assert.Len(t, after.Houses, 2)
+
+	_, err = houseUsecase.SearchByAddress(ctx, &domain.HouseAddressSearchRequest{Query: " ,. "}, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_BadQuery)
+}
+
+func TestGetHouseStats(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	initDB("")
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := pool.Exec(ctx, `insert into flats(flat_id, house_id, user_id, price, rooms, status)
+		values (1, 2, '019126ee-2b7d-758e-bb22-fe2e45b2db22', 100, 1, 'approved'),
+		       (2, 2, '019126ee-2b7d-758e-bb22-fe2e45b2db22', 300, 2, 'approved'),
+		       (3, 2, '019126ee-2b7d-758e-bb22-fe2e45b2db22', 200, 2, 'approved'),
+		       (4, 2, '019126ee-2b7d-758e-bb22-fe2e45b2db22', 500, 3, 'created')`)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+
+	stats, err := houseUsecase.GetStats(ctx, 2, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Equal(t, domain.HouseStatsResponse{
+		HouseID:         2,
+		ApprovedFlats:   3,
+		PriceMin:        100,
+		PriceMedian:     200,
+		PriceMax:        300,
+		AvgPricePerRoom: 120,
+		Rooms: []domain.HouseRoomsStatsResponse{
+			{Rooms: 1, Flats: 1, PriceMin: 100, PriceMedian: 100, PriceMax: 100, PriceAvg: 100},
+			{Rooms: 2, Flats: 2, PriceMin: 200, PriceMedian: 250, PriceMax: 300, PriceAvg: 250},
+		},
+	}, stats)
+
+	// the counters follow flats leaving the approved status
+	_, err = pool.Exec(ctx, `update flats set status='archived' where house_id=2 and flat_id=2`)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	stats, err = houseUsecase.GetStats(ctx, 2, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Equal(t, 2, stats.ApprovedFlats)
+	assert.Equal(t, float64(150), stats.PriceMedian)
+	assert.Equal(t, 200, stats.PriceMax)
+	assert.Equal(t, float64(100), stats.AvgPricePerRoom)
+
+	empty, err := houseUsecase.GetStats(ctx, 1, lg)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, empty.ApprovedFlats)
+	assert.Empty(t, empty.Rooms)
+
+	_, err = houseUsecase.GetStats(ctx, 100, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_NotFound)
+}
+
+func TestFlatStatusChangeBumpsHouseUpdateDate(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	flatUsecase, _, flatPool := initFlatEnv()
+	initDB("")
+	defer pool.Close()
+	defer flatPool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	before, err := houseUsecase.GetFlatsUpdateDate(ctx, 1, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+
+	modID := uuid.MustParse("019126ee-2b7d-758e-bb22-fe2e45b2db23")
+	_, err = flatUsecase.Update(ctx, modID, &domain.UpdateFlatRequest{ID: 10, HouseID: 1, Status: "on moderation"}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+
+	after, err := houseUsecase.GetFlatsUpdateDate(ctx, 1, lg)
+	assert.NoError(t, err)
+	assert.True(t, after.After(before))
+
+	// the other house keeps its date
+	other, err := houseUsecase.GetFlatsUpdateDate(ctx, 2, lg)
+	assert.NoError(t, err)
+	assert.True(t, other.Before(after))
+
+	_, err = houseUsecase.GetFlatsUpdateDate(ctx, 100, lg)
+	assert.ErrorIs(t, err, domain.ErrHouse_NotFound)
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"avito-test-task/internal/ports"
+	"avito-test-task/internal/ports/smtpstub"
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSMTPSender(t *testing.T, addr string, password string) *ports.SMTPSender {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, _ := strconv.Atoi(port)
+
+	sender, err := ports.NewSMTPSender(ports.SMTPSettings{
+		Host:               host,
+		Port:               portNumber,
+		Username:           "notifier",
+		Password:           password,
+		From:               "Avito flats <noreply@example.com>",
+		Subject:            "Новая квартира в вашем доме",
+		TLSMode:            ports.SMTPStartTLS,
+		InsecureSkipVerify: true,
+		Timeout:            5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sender
+}
+
+func TestSMTPSender(t *testing.T) {
+	server, err := smtpstub.Start(smtpstub.Options{Username: "notifier", Password: "secret", StartTLS: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	sender := newTestSMTPSender(t, server.Addr, "secret")
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	link := "http://localhost/unsubscribe?token=a.b"
+	text := "New flat 7 in house 1\nUnsubscribe: " + link
+	for _, recipient := range []string{"first@example.com", "second@example.com"} {
+		err = sender.SendEmail(ctx, recipient, text, link)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+	}
+
+	messages := server.Messages()
+	if !assert.Len(t, messages, 2) {
+		return
+	}
+	assert.Equal(t, 1, server.Connections())
+	assert.Equal(t, []string{"second@example.com"}, messages[1].To)
+	assert.Equal(t, "noreply@example.com", messages[0].From)
+	assert.True(t, messages[0].TLS)
+	assert.Equal(t, "notifier", messages[0].User)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(messages[0].Data))
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Новая квартира в вашем доме", subject)
+	assert.Equal(t, "<"+link+">", msg.Header.Get("List-Unsubscribe"))
+	assert.Equal(t, "List-Unsubscribe=One-Click", msg.Header.Get("List-Unsubscribe-Post"))
+	to, err := msg.Header.AddressList("To")
+	if assert.NoError(t, err) && assert.Len(t, to, 1) {
+		assert.Equal(t, "first@example.com", to[0].Address)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		assert.NoError(t, err)
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[partType] = string(body)
+	}
+	assert.Equal(t, text, parts["text/plain"])
+	assert.True(t, strings.Contains(parts["text/html"],
+		`<a href="http://localhost/unsubscribe?token=a.b">`), parts["text/html"])
+	assert.True(t, strings.Contains(parts["text/html"], "New flat 7 in house 1<br>"), parts["text/html"])
+
+	// a dropped idle connection is replaced without losing the message
+	server.DropConnections()
+	err = sender.SendEmail(ctx, "third@example.com", text, link)
+	assert.NoError(t, err)
+	assert.Len(t, server.Messages(), 3)
+	assert.Equal(t, 2, server.Connections())
+
+	// the server may have queued a message whose reply was lost, so it is not sent again
+	server.HangUpAfterNextData()
+	err = sender.SendEmail(ctx, "fourth@example.com", text, link)
+	assert.Error(t, err)
+	assert.Len(t, server.Messages(), 4)
+	assert.Equal(t, 2, server.Connections())
+
+	// the next message goes through a new connection
+	err = sender.SendEmail(ctx, "fifth@example.com", text, link)
+	assert.NoError(t, err)
+	assert.Len(t, server.Messages(), 5)
+	assert.Equal(t, 3, server.Connections())
+}
+
+func TestSMTPSenderWrongPassword(t *testing.T) {
+	server, err := smtpstub.Start(smtpstub.Options{Username: "notifier", Password: "secret", StartTLS: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	sender := newTestSMTPSender(t, server.Addr, "wrong")
+	defer sender.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = sender.SendEmail(ctx, "first@example.com", "New flat 7 in house 1", "")
+	assert.Error(t, err)
+	assert.Empty(t, server.Messages())
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"avito-test-task/internal/domain"
+	"avito-test-task/pkg"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	key := pkg.Key
+	t.Cleanup(func() { pkg.Key = key })
+	pkg.Key = "test-key"
+	userID := uuid.MustParse("019126ee-2b7d-758e-bb22-fe2e45b2db22")
+
+	token := pkg.GenerateUnsubscribeToken(userID, 42)
+	parsedUserID, houseID, err := pkg.ParseUnsubscribeToken(token)
+	if assert.NoError(t, err) {
+		assert.Equal(t, userID, parsedUserID)
+		assert.Equal(t, 42, houseID)
+	}
+
+	other := pkg.GenerateUnsubscribeToken(userID, 43)
+	payload, _, _ := strings.Cut(other, ".")
+	_, signature, _ := strings.Cut(token, ".")
+	_, _, err = pkg.ParseUnsubscribeToken(payload + "." + signature)
+	assert.Error(t, err)
+
+	pkg.Key = "other-key"
+	_, _, err = pkg.ParseUnsubscribeToken(token)
+	assert.Error(t, err)
+
+	for _, bad := range []string{"", ".", "abc", token + "x"} {
+		_, _, err = pkg.ParseUnsubscribeToken(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	initDB("")
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID := uuid.MustParse("019126ee-2b7d-758e-bb22-fe2e45b2db22")
+	for _, houseID := range []int{1, 2} {
+		err := houseUsecase.SubscribeByID(ctx, houseID, userID, lg)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+	}
+
+	resp, err := houseUsecase.GetSubscriptions(ctx, userID, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Len(t, resp.Subscriptions, 2)
+
+	err = houseUsecase.UnsubscribeByID(ctx, 1, userID, lg)
+	assert.NoError(t, err)
+	err = houseUsecase.UnsubscribeByID(ctx, 1, userID, lg)
+	assert.ErrorIs(t, err, domain.ErrSubscription_NotFound)
+
+	token := pkg.GenerateUnsubscribeToken(userID, 2)
+
+	// opening the link only shows the subscription
+	checked, err := houseUsecase.CheckUnsubscribeToken(ctx, token, lg)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.UnsubscribeResponse{HouseID: 2, Subscribed: true}, checked)
+	resp, err = houseUsecase.GetSubscriptions(ctx, userID, lg)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Subscriptions, 1)
+
+	unsubscribed, err := houseUsecase.UnsubscribeByToken(ctx, token, lg)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.UnsubscribeResponse{HouseID: 2}, unsubscribed)
+
+	checked, err = houseUsecase.CheckUnsubscribeToken(ctx, token, lg)
+	assert.NoError(t, err)
+	assert.False(t, checked.Subscribed)
+
+	// the link keeps working after the subscription is gone
+	_, err = houseUsecase.UnsubscribeByToken(ctx, token, lg)
+	assert.NoError(t, err)
+
+	resp, err = houseUsecase.GetSubscriptions(ctx, userID, lg)
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Subscriptions)
+
+	_, err = houseUsecase.UnsubscribeByToken(ctx, "bad", lg)
+	assert.ErrorIs(t, err, domain.ErrSubscription_BadToken)
+	_, err = houseUsecase.CheckUnsubscribeToken(ctx, "bad", lg)
+	assert.ErrorIs(t, err, domain.ErrSubscription_BadToken)
+}
+
+func TestNotifySubscribersOnApproval(t *testing.T) {
+	houseUsecase, lg, pool := initHouseEnv()
+	flatUsecase, _, flatPool := initFlatEnv()
+	initDB("")
+	defer pool.Close()
+	defer flatPool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID := uuid.MustParse("019126ee-2b7d-758e-bb22-fe2e45b2db22")
+	modID := uuid.MustParse("019126ee-2b7d-758e-bb22-fe2e45b2db23")
+	err := houseUsecase.SubscribeByID(ctx, 1, userID, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+
+	notifies := func(flatID int) int {
+		var count int
+		err := pool.QueryRow(ctx, `select count(*) from new_flats_outbox where house_id=1 and flat_id=$1`,
+			flatID).Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+	approve := func() {
+		for _, status := range []string{domain.ModeratingStatus, domain.ApprovedStatus} {
+			_, err := flatUsecase.Update(ctx, modID, &domain.UpdateFlatRequest{ID: 10, HouseID: 1, Status: status}, lg)
+			assert.NoError(t, err)
+		}
+	}
+
+	created, err := flatUsecase.Create(ctx, userID, &domain.CreateFlatRequest{HouseID: 1, Price: 1000, Rooms: 2}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	assert.Equal(t, 0, notifies(created.ID))
+
+	approve()
+	assert.Equal(t, 1, notifies(10))
+
+	// an edit sends the flat back to moderation, the second approval is not announced again
+	price := 150
+	_, err = flatUsecase.Edit(ctx, userID, &domain.EditFlatRequest{ID: 10, HouseID: 1, Price: &price}, lg)
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	approve()
+	assert.Equal(t, 1, notifies(10))
+}